@@ -0,0 +1,58 @@
+// Command create-api-key creates an API key and prints it. The key cannot be
+// recovered afterwards, since only its hash is stored.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/auth"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+	"github.com/google/uuid"
+)
+
+func main() {
+	name := flag.String("name", "", "name describing the key")
+	subject := flag.String("subject", "", "subject the key authenticates as")
+	scopes := flag.String("scopes", auth.ScopeUploadRead+","+auth.ScopeUploadWrite, "comma separated scopes")
+	expires := flag.Duration("expires", 0, "how long the key is valid for, or 0 for no expiry")
+	flag.Parse()
+	if *name == "" || *subject == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dbConfig, err := db.ConfigFromEnv(os.Getenv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading database config: %s\n", err)
+		os.Exit(1)
+	}
+	pool, closePool, err := db.InitDBPool(dbConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to database: %s\n", err)
+		os.Exit(1)
+	}
+	defer closePool()
+
+	apiKey := db.APIKey{
+		ID:      uuid.New(),
+		Name:    *name,
+		Subject: *subject,
+		Scopes:  strings.Split(*scopes, ","),
+	}
+	if *expires > 0 {
+		expiresAt := time.Now().Add(*expires)
+		apiKey.ExpiresAt = &expiresAt
+	}
+	key, keyHash := auth.NewAPIKey()
+	ctx := db.WithConnPool(context.Background(), pool)
+	if err := db.CreateAPIKey(ctx, apiKey, keyHash); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating API key: %s\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(key)
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/storage"
+)
+
+type Config struct {
+	// SignedURLTTL is how long a signed part upload URL stays valid.
+	SignedURLTTL time.Duration
+	// TusMaxSize is the largest upload accepted over tus, in bytes.
+	TusMaxSize int64
+	// TusUploadTTL is how long a tus upload may go without receiving data
+	// before it expires. Every stored segment pushes the expiry back.
+	TusUploadTTL time.Duration
+	// TusSegmentSize is the largest segment a PATCH request is stored in, in
+	// bytes.
+	TusSegmentSize int64
+}
+
+func DefaultConfig() Config {
+	return Config{
+		SignedURLTTL:   15 * time.Minute,
+		TusMaxSize:     1 << 40,
+		TusUploadTTL:   24 * time.Hour,
+		TusSegmentSize: 64 << 20,
+	}
+}
+
+// ConfigFromEnv reads API_SIGNED_URL_TTL, API_TUS_MAX_SIZE, API_TUS_UPLOAD_TTL
+// and API_TUS_SEGMENT_SIZE, falling back to DefaultConfig for unset variables.
+func ConfigFromEnv(getenv func(string) string) (Config, error) {
+	config := DefaultConfig()
+	var err error
+	if value := getenv("API_SIGNED_URL_TTL"); value != "" {
+		if config.SignedURLTTL, err = time.ParseDuration(value); err != nil || config.SignedURLTTL <= 0 {
+			return config, fmt.Errorf("invalid API_SIGNED_URL_TTL: %q", value)
+		}
+	}
+	if value := getenv("API_TUS_MAX_SIZE"); value != "" {
+		if config.TusMaxSize, err = strconv.ParseInt(value, 10, 64); err != nil || config.TusMaxSize <= 0 {
+			return config, fmt.Errorf("invalid API_TUS_MAX_SIZE: %q", value)
+		}
+	}
+	if value := getenv("API_TUS_UPLOAD_TTL"); value != "" {
+		if config.TusUploadTTL, err = time.ParseDuration(value); err != nil || config.TusUploadTTL <= 0 {
+			return config, fmt.Errorf("invalid API_TUS_UPLOAD_TTL: %q", value)
+		}
+	}
+	if value := getenv("API_TUS_SEGMENT_SIZE"); value != "" {
+		if config.TusSegmentSize, err = strconv.ParseInt(value, 10, 64); err != nil || config.TusSegmentSize <= 0 {
+			return config, fmt.Errorf("invalid API_TUS_SEGMENT_SIZE: %q", value)
+		}
+	}
+	return config, nil
+}
+
+// API serves the upload routes. Handlers reach the database through the pool
+// or transaction in the request context, and objects through bucket.
+type API struct {
+	config    Config
+	bucket    *storage.Bucket
+	streaming streamingParts
+}
+
+func New(bucket *storage.Bucket, config Config) *API {
+	return &API{
+		config:    config,
+		bucket:    bucket,
+		streaming: streamingParts{parts: map[partKey]db.Part{}},
+	}
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/storage"
+	"github.com/google/uuid"
+)
+
+// newTestAPI creates an API on an in-memory store.
+func newTestAPI() *API {
+	return New(storage.NewBucket(storage.NewMemoryStore()), DefaultConfig())
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("API_SIGNED_URL_TTL", "5m")
+	t.Setenv("API_TUS_MAX_SIZE", "1024")
+	t.Setenv("API_TUS_UPLOAD_TTL", "1h")
+	t.Setenv("API_TUS_SEGMENT_SIZE", "256")
+
+	config, err := ConfigFromEnv(os.Getenv)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := Config{SignedURLTTL: 5 * time.Minute, TusMaxSize: 1024, TusUploadTTL: time.Hour, TusSegmentSize: 256}
+	if config != expected {
+		t.Fatalf("Expected %+v, got %+v", expected, config)
+	}
+}
+
+func TestConfigFromEnv_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"API_SIGNED_URL_TTL":   "0s",
+		"API_TUS_MAX_SIZE":     "1TiB",
+		"API_TUS_UPLOAD_TTL":   "soon",
+		"API_TUS_SEGMENT_SIZE": "-1",
+	}
+	for name, value := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			if _, err := ConfigFromEnv(os.Getenv); err == nil {
+				t.Fatalf("Expected error for %s=%q, got nil", name, value)
+			}
+		})
+	}
+}
+
+func TestTrackStreaming(t *testing.T) {
+	t.Parallel()
+	a, b := newTestAPI(), newTestAPI()
+	done := a.trackStreaming(db.Part{UploadID: uuid.New(), PartNumber: 1})
+
+	// Parts are tracked per API, so b has none to fail
+	if failed := b.FailInterruptedParts(context.Background()); failed != 0 {
+		t.Fatalf("Expected no interrupted parts, got %d", failed)
+	}
+	done()
+	if failed := a.FailInterruptedParts(context.Background()); failed != 0 {
+		t.Fatalf("Expected no interrupted parts once done, got %d", failed)
+	}
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/logging"
+	"github.com/google/uuid"
+)
+
+type completeUploadResponse struct {
+	ID              uuid.UUID `json:"id"`
+	ObjectKey       string    `json:"object_key"`
+	Size            int       `json:"size"`
+	MimeType        string    `json:"mime_type"`
+	Sha256          *string   `json:"sha256,omitempty"`
+	CompositeSha256 *string   `json:"composite_sha256,omitempty"`
+	FinalizedAt     time.Time `json:"finalized_at"`
+}
+
+// CompleteUpload handles POST /uploads/{id}/complete. Once every part has been
+// uploaded, the parts are composed in order into the final object, which is
+// checked against the declared size and recorded with the digest derived from
+// the part digests by uploadDigest. The part objects are then deleted.
+// Completing an already finalized upload is a no-op.
+//
+// The response carries the SHA-256 of the file as sha256 for uploads with a
+// single part, and otherwise the SHA-256 of the concatenated SHA-256 of every
+// part, in part order, as composite_sha256.
+func (a *API) CompleteUpload(w http.ResponseWriter, r *http.Request) {
+	id, err := uploadIDFromPath(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	owner := requestOwner(r)
+	upload, err := db.GetUpload(ctx, owner, id)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	if !upload.Finalized() {
+		err = a.finalizeUpload(ctx, upload)
+		var notCompleted db.ErrUploadNotCompleted
+		var alreadyFinalized db.ErrUploadAlreadyFinalized
+		var invalid errInvalidParts
+		switch {
+		case errors.As(err, &notCompleted):
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		case errors.As(err, &invalid):
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		case errors.As(err, &alreadyFinalized):
+			// Another request finalized the upload first
+		case err != nil:
+			writeDBError(w, r, err)
+			return
+		}
+		upload, err = db.GetUpload(ctx, owner, id)
+		if err != nil {
+			writeInternalError(w, r, err)
+			return
+		}
+	}
+
+	sha256, compositeSha256 := uploadDigests(upload)
+	writeJSON(w, http.StatusOK, completeUploadResponse{
+		ID:              upload.ID,
+		ObjectKey:       *upload.ObjectKey,
+		Size:            upload.Size,
+		MimeType:        upload.MimeType,
+		Sha256:          sha256,
+		CompositeSha256: compositeSha256,
+		FinalizedAt:     *upload.FinalizedAt,
+	})
+}
+
+type errInvalidParts struct {
+	reason string
+}
+
+func (e errInvalidParts) Error() string {
+	return e.reason
+}
+
+// finalizeUpload composes the uploaded parts of a completed upload into its
+// final object and records it. The upload must have been loaded for its owner.
+func (a *API) finalizeUpload(ctx context.Context, upload *db.Upload) error {
+	if upload.Status != db.UploadStatusCompleted {
+		return db.ErrUploadNotCompleted{UploadID: upload.ID}
+	}
+	parts, err := db.GetUploadParts(ctx, *upload.OwnerID, upload.ID)
+	if err != nil {
+		return err
+	}
+
+	objectKeys := make([]string, len(parts))
+	var total int64
+	for i, part := range parts {
+		if part.Status != db.PartStatusUploaded || part.ByteSize == nil {
+			return errInvalidParts{fmt.Sprintf("part %d is not uploaded", part.PartNumber)}
+		}
+		objectKeys[i] = part.ObjectKey
+		total += *part.ByteSize
+	}
+	if total != int64(upload.Size) {
+		return errInvalidParts{fmt.Sprintf("parts add up to %d bytes, expected %d", total, upload.Size)}
+	}
+
+	digest, err := uploadDigest(parts)
+	if err != nil {
+		return errInvalidParts{err.Error()}
+	}
+
+	// Keep the garbage collector away while the parts are composed
+	if err := db.TouchUpload(ctx, upload.ID); err != nil {
+		return err
+	}
+	objectKey := db.FinalObjectKey(upload.ID)
+	if err := a.bucket.ComposeAll(ctx, objectKey, objectKeys); err != nil {
+		return fmt.Errorf("composing parts: %w", err)
+	}
+	attrs, err := a.bucket.Attrs(ctx, objectKey)
+	if err != nil {
+		return err
+	}
+	if attrs.Size != int64(upload.Size) {
+		return fmt.Errorf("composed object is %d bytes, expected %d", attrs.Size, upload.Size)
+	}
+	if err := db.FinalizeUpload(ctx, upload.ID, objectKey, attrs.Size, digest); err != nil {
+		return err
+	}
+
+	for _, key := range objectKeys {
+		if err := a.bucket.Delete(ctx, key); err != nil {
+			logging.FromContext(ctx).Error("Failed to delete part object", "upload_id", upload.ID, "object_key", key, "error", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+)
+
+// parseContentDigest returns the sha-256 digest from a Content-Digest header
+// (RFC 9530), e.g. `sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:`.
+// It returns nil if the header is absent or carries no sha-256 digest.
+func parseContentDigest(header http.Header) ([]byte, error) {
+	value := header.Get("Content-Digest")
+	if value == "" {
+		return nil, nil
+	}
+	for _, member := range strings.Split(value, ",") {
+		algorithm, digest, ok := strings.Cut(strings.TrimSpace(member), "=")
+		if !ok {
+			return nil, errors.New("malformed Content-Digest header")
+		}
+		if strings.ToLower(algorithm) != "sha-256" {
+			continue
+		}
+		if len(digest) < 2 || digest[0] != ':' || digest[len(digest)-1] != ':' {
+			return nil, errors.New("malformed sha-256 Content-Digest")
+		}
+		decoded, err := base64.StdEncoding.DecodeString(digest[1 : len(digest)-1])
+		if err != nil {
+			return nil, errors.New("malformed sha-256 Content-Digest")
+		}
+		if len(decoded) != 32 {
+			return nil, errors.New("sha-256 Content-Digest must be 32 bytes")
+		}
+		return decoded, nil
+	}
+	return nil, nil
+}
+
+// uploadDigest derives the digest of an upload from the SHA-256 of its parts,
+// in part order, so that the final object never has to be read back. An upload
+// with a single part, which includes every tus upload, gets the SHA-256 of the
+// file. Otherwise the digest is the SHA-256 of the concatenated part digests,
+// which uploadDigests reports as the composite SHA-256 rather than the SHA-256.
+func uploadDigest(parts []db.Part) ([]byte, error) {
+	hash := sha256.New()
+	for _, part := range parts {
+		if part.Sha256 == nil {
+			return nil, fmt.Errorf("part %d has no SHA-256", part.PartNumber)
+		}
+		if len(parts) == 1 {
+			return *part.Sha256, nil
+		}
+		hash.Write(*part.Sha256)
+	}
+	return hash.Sum(nil), nil
+}
+
+// uploadDigests returns the recorded digest of a finalized upload in hex, as
+// the SHA-256 of the file if it has a single part and as the composite SHA-256
+// of its parts otherwise. The other is nil.
+func uploadDigests(upload *db.Upload) (sha256 *string, compositeSha256 *string) {
+	if upload.Sha256 == nil {
+		return nil, nil
+	}
+	digest := hex.EncodeToString(*upload.Sha256)
+	if upload.PartsCount == 1 {
+		return &digest, nil
+	}
+	return nil, &digest
+}
+
+// uploadETag returns the ETag of a finalized upload. The composite SHA-256 of
+// an upload with several parts is suffixed with the number of parts, so that
+// it cannot be mistaken for the SHA-256 of the file.
+func uploadETag(upload *db.Upload) string {
+	digest := hex.EncodeToString(*upload.Sha256)
+	if upload.PartsCount == 1 {
+		return `"` + digest + `"`
+	}
+	return fmt.Sprintf(`"%s-%d"`, digest, upload.PartsCount)
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+)
+
+func TestParseContentDigest(t *testing.T) {
+	t.Parallel()
+	sum := sha256.Sum256([]byte("hello"))
+	encoded := base64.StdEncoding.EncodeToString(sum[:])
+
+	header := http.Header{}
+	header.Set("Content-Digest", "sha-512=:AAAA:, sha-256=:"+encoded+":")
+	digest, err := parseContentDigest(header)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if string(digest) != string(sum[:]) {
+		t.Fatalf("Expected digest %x, got %x", sum, digest)
+	}
+
+	digest, err = parseContentDigest(http.Header{})
+	if err != nil || digest != nil {
+		t.Fatalf("Expected no digest without header, got %x, %v", digest, err)
+	}
+
+	for _, value := range []string{"sha-256", "sha-256=" + encoded, "sha-256=:AAAA:"} {
+		header.Set("Content-Digest", value)
+		if _, err := parseContentDigest(header); err == nil {
+			t.Fatalf("Expected error for %q, got none", value)
+		}
+	}
+}
+
+func TestUploadDigest(t *testing.T) {
+	t.Parallel()
+	first := sha256.Sum256([]byte("hello "))
+	second := sha256.Sum256([]byte("world"))
+	part := func(number int, sum []byte) db.Part {
+		return db.Part{PartNumber: number, Sha256: &sum}
+	}
+
+	digest, err := uploadDigest([]db.Part{part(0, first[:])})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if string(digest) != string(first[:]) {
+		t.Fatalf("Expected the part digest %x for a single part, got %x", first, digest)
+	}
+
+	digest, err = uploadDigest([]db.Part{part(0, first[:]), part(1, second[:])})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := sha256.Sum256(append(first[:], second[:]...))
+	if string(digest) != string(expected[:]) {
+		t.Fatalf("Expected digest %x, got %x", expected, digest)
+	}
+
+	if _, err := uploadDigest([]db.Part{part(0, first[:]), {PartNumber: 1}}); err == nil {
+		t.Fatalf("Expected error for a part without a digest, got none")
+	}
+}
+
+func TestUploadDigests(t *testing.T) {
+	t.Parallel()
+	digest := []byte{0xab, 0xcd}
+	upload := &db.Upload{PartsCount: 1, Sha256: &digest}
+	sha256, composite := uploadDigests(upload)
+	if sha256 == nil || *sha256 != "abcd" || composite != nil {
+		t.Fatalf("Expected sha256 abcd for a single part, got %v and %v", sha256, composite)
+	}
+	if etag := uploadETag(upload); etag != `"abcd"` {
+		t.Fatalf(`Expected ETag "abcd", got %s`, etag)
+	}
+
+	upload.PartsCount = 3
+	sha256, composite = uploadDigests(upload)
+	if sha256 != nil || composite == nil || *composite != "abcd" {
+		t.Fatalf("Expected composite_sha256 abcd for several parts, got %v and %v", sha256, composite)
+	}
+	if etag := uploadETag(upload); etag != `"abcd-3"` {
+		t.Fatalf(`Expected ETag "abcd-3", got %s`, etag)
+	}
+
+	upload.Sha256 = nil
+	if sha256, composite := uploadDigests(upload); sha256 != nil || composite != nil {
+		t.Fatalf("Expected no digest before finalizing, got %v and %v", sha256, composite)
+	}
+}
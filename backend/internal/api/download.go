@@ -0,0 +1,87 @@
+package api
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/metrics"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/storage"
+)
+
+// DownloadUpload handles GET /uploads/{id}/content. The final object is
+// streamed with http.ServeContent, which takes care of Range, If-Range,
+// If-None-Match and If-Modified-Since and answers 206, 304 or 416 as needed.
+// The ETag is made by uploadETag from the digest recorded when the upload was
+// finalized.
+func (a *API) DownloadUpload(w http.ResponseWriter, r *http.Request) {
+	id, err := uploadIDFromPath(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	upload, err := db.GetUpload(ctx, requestOwner(r), id)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	if !upload.Finalized() {
+		writeError(w, http.StatusConflict, "upload is not finalized")
+		return
+	}
+
+	a.serveUpload(w, r, upload)
+}
+
+// serveUpload streams the final object of a finalized upload.
+func (a *API) serveUpload(w http.ResponseWriter, r *http.Request, upload *db.Upload) {
+	object := storage.NewObjectReadSeeker(r.Context(), a.bucket.Store(), *upload.ObjectKey, int64(upload.Size))
+	defer object.Close()
+	content := &countingReadSeeker{ReadSeeker: object}
+	defer func() {
+		metrics.TransferredBytes.WithLabelValues(metrics.DirectionDownload).Add(float64(content.n))
+	}()
+
+	w.Header().Set("Content-Type", upload.MimeType)
+	w.Header().Set("ETag", uploadETag(upload))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": upload.ID.String() + fileExtension(upload.MimeType),
+	}))
+	w.Header().Set("Cache-Control", "private, no-cache")
+	http.ServeContent(w, r, "", *upload.FinalizedAt, content)
+}
+
+// countingReadSeeker counts the bytes read through it.
+type countingReadSeeker struct {
+	io.ReadSeeker
+	n int64
+}
+
+func (r *countingReadSeeker) Read(p []byte) (int, error) {
+	n, err := r.ReadSeeker.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// fileExtension picks a file extension for a MIME type, preferring the one
+// named after the subtype (".jpeg" over ".jfif" for image/jpeg).
+func fileExtension(mimeType string) string {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return ""
+	}
+	extensions, err := mime.ExtensionsByType(mediaType)
+	if err != nil || len(extensions) == 0 {
+		return ""
+	}
+	_, subtype, _ := strings.Cut(mediaType, "/")
+	if slices.Contains(extensions, "."+subtype) {
+		return "." + subtype
+	}
+	return extensions[0]
+}
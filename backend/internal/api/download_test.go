@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+	"github.com/google/uuid"
+)
+
+func TestFileExtension(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"image/png":                ".png",
+		"image/jpeg":               ".jpeg",
+		"application/pdf; q=1":     ".pdf",
+		"application/x-not-a-type": "",
+		"not a mime type":          "",
+	}
+	for mimeType, want := range cases {
+		if got := fileExtension(mimeType); got != want {
+			t.Fatalf("Expected extension %q for %s, got %q", want, mimeType, got)
+		}
+	}
+}
+
+// newTestUpload stores content as the final object of a finalized upload.
+func newTestUpload(t *testing.T, a *API, content string) *db.Upload {
+	id := uuid.New()
+	objectKey := db.FinalObjectKey(id)
+	if err := a.bucket.Upload(context.Background(), objectKey, []byte(content)); err != nil {
+		t.Fatalf("Failed to upload object: %v", err)
+	}
+	sum := sha256.Sum256([]byte(content))
+	digest := sum[:]
+	finalizedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return &db.Upload{
+		ID:          id,
+		PartsCount:  1,
+		Size:        len(content),
+		MimeType:    "text/plain",
+		Status:      db.UploadStatusCompleted,
+		ObjectKey:   &objectKey,
+		Sha256:      &digest,
+		FinalizedAt: &finalizedAt,
+	}
+}
+
+func TestServeUpload(t *testing.T) {
+	t.Parallel()
+	a := newTestAPI()
+	upload := newTestUpload(t, a, "0123456789")
+
+	w := httptest.NewRecorder()
+	a.serveUpload(w, httptest.NewRequest(http.MethodGet, "/uploads/"+upload.ID.String()+"/content", nil), upload)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if w.Body.String() != "0123456789" {
+		t.Fatalf("Expected body 0123456789, got %q", w.Body.String())
+	}
+	if w.Header().Get("Accept-Ranges") != "bytes" {
+		t.Fatalf("Expected Accept-Ranges bytes, got %q", w.Header().Get("Accept-Ranges"))
+	}
+}
+
+func TestServeUpload_Range(t *testing.T) {
+	t.Parallel()
+	a := newTestAPI()
+	upload := newTestUpload(t, a, "0123456789")
+	cases := []struct {
+		rangeHeader  string
+		status       int
+		body         string
+		contentRange string
+	}{
+		{"bytes=2-5", http.StatusPartialContent, "2345", "bytes 2-5/10"},
+		{"bytes=7-", http.StatusPartialContent, "789", "bytes 7-9/10"},
+		{"bytes=10-20", http.StatusRequestedRangeNotSatisfiable, "", "bytes */10"},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/uploads/"+upload.ID.String()+"/content", nil)
+		r.Header.Set("Range", c.rangeHeader)
+		w := httptest.NewRecorder()
+		a.serveUpload(w, r, upload)
+		if w.Code != c.status {
+			t.Fatalf("Expected status %d for %s, got %d", c.status, c.rangeHeader, w.Code)
+		}
+		if w.Header().Get("Content-Range") != c.contentRange {
+			t.Fatalf("Expected Content-Range %q for %s, got %q", c.contentRange, c.rangeHeader, w.Header().Get("Content-Range"))
+		}
+		if c.status == http.StatusPartialContent && w.Body.String() != c.body {
+			t.Fatalf("Expected body %q for %s, got %q", c.body, c.rangeHeader, w.Body.String())
+		}
+	}
+}
+
+func TestServeUpload_NotModified(t *testing.T) {
+	t.Parallel()
+	a := newTestAPI()
+	upload := newTestUpload(t, a, "0123456789")
+
+	w := httptest.NewRecorder()
+	a.serveUpload(w, httptest.NewRequest(http.MethodGet, "/uploads/"+upload.ID.String()+"/content", nil), upload)
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("Expected an ETag")
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/uploads/"+upload.ID.String()+"/content", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	a.serveUpload(w, r, upload)
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("Expected status 304, got %d", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Fatalf("Expected no body, got %q", w.Body.String())
+	}
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+	"github.com/google/uuid"
+)
+
+type partKey struct {
+	uploadID   uuid.UUID
+	partNumber int
+}
+
+// streamingParts are the parts whose content is being received, so that
+// those cut off when the server stops can be marked as failed.
+type streamingParts struct {
+	sync.Mutex
+	parts map[partKey]db.Part
+}
+
+// trackStreaming records that part is being received until the returned
+// function is called.
+func (a *API) trackStreaming(part db.Part) func() {
+	key := partKey{uploadID: part.UploadID, partNumber: part.PartNumber}
+	a.streaming.Lock()
+	a.streaming.parts[key] = part
+	a.streaming.Unlock()
+	return func() {
+		a.streaming.Lock()
+		delete(a.streaming.parts, key)
+		a.streaming.Unlock()
+	}
+}
+
+// FailInterruptedParts marks the parts still being received as failed, so
+// that clients retry them rather than wait on them. It is meant to be called
+// once the server has stopped serving requests, and returns how many parts
+// were failed.
+func (a *API) FailInterruptedParts(ctx context.Context) int {
+	a.streaming.Lock()
+	parts := make([]db.Part, 0, len(a.streaming.parts))
+	for key, part := range a.streaming.parts {
+		parts = append(parts, part)
+		delete(a.streaming.parts, key)
+	}
+	a.streaming.Unlock()
+
+	for _, part := range parts {
+		failPart(ctx, part)
+	}
+	return len(parts)
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/logging"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/problem"
+)
+
+const maxJSONBodySize = 1 << 20
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Failed to write JSON response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	problem.Write(w, status, message)
+}
+
+// writeInternalError logs err and hides it from the client.
+func writeInternalError(w http.ResponseWriter, r *http.Request, err error) {
+	logging.FromContext(r.Context()).Error("Internal error", "method", r.Method, "path", r.URL.Path, "error", err)
+	writeError(w, http.StatusInternalServerError, "internal server error")
+}
+
+// writeDBError responds with the status problem.StatusOf maps err to, or as
+// an internal error if it has none.
+func writeDBError(w http.ResponseWriter, r *http.Request, err error) {
+	if status, ok := problem.StatusOf(err); ok {
+		writeError(w, status, err.Error())
+		return
+	}
+	writeInternalError(w, r, err)
+}
+
+// decodeJSON decodes a single JSON object from the request body, rejecting
+// unknown fields and trailing data.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxJSONBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid JSON body: %w", err)
+	}
+	if decoder.More() {
+		return errors.New("invalid JSON body: unexpected data after object")
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/auth"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/logging"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/metrics"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/storage"
+	"github.com/google/uuid"
+)
+
+type uploadPartResponse struct {
+	UploadID     uuid.UUID       `json:"upload_id"`
+	PartNumber   int             `json:"part_number"`
+	Status       db.PartStatus   `json:"status"`
+	ByteOffset   int64           `json:"byte_offset"`
+	ByteSize     int64           `json:"byte_size"`
+	Sha256       string          `json:"sha256"`
+	UploadStatus db.UploadStatus `json:"upload_status"`
+}
+
+func uploadIDFromPath(r *http.Request) (uuid.UUID, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid upload ID: %q", r.PathValue("id"))
+	}
+	return id, nil
+}
+
+// requestOwner returns the user making the request, who may only see their own
+// uploads. Unauthenticated requests get uuid.Nil, which owns nothing.
+func requestOwner(r *http.Request) uuid.UUID {
+	principal, ok := auth.PrincipalFrom(r.Context())
+	if !ok {
+		return uuid.Nil
+	}
+	return principal.UserID
+}
+
+func partNumberFromPath(r *http.Request) (int, error) {
+	partNumber, err := strconv.Atoi(r.PathValue("n"))
+	if err != nil || partNumber < 0 {
+		return 0, fmt.Errorf("invalid part number: %q", r.PathValue("n"))
+	}
+	return partNumber, nil
+}
+
+func findPart(parts []db.Part, partNumber int) (db.Part, bool) {
+	for _, part := range parts {
+		if part.PartNumber == partNumber {
+			return part, true
+		}
+	}
+	return db.Part{}, false
+}
+
+// UploadPart handles PUT /uploads/{id}/parts/{n}. The request body is streamed
+// into the part's object while being hashed, and the part is marked uploaded
+// once its size and SHA-256 check out. A sha-256 Content-Digest header, if
+// present, must match the received bytes.
+func (a *API) UploadPart(w http.ResponseWriter, r *http.Request) {
+	id, err := uploadIDFromPath(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	partNumber, err := partNumberFromPath(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	expectedDigest, err := parseContentDigest(r.Header)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	upload, part, ok := getPendingPart(w, r, id, partNumber)
+	if !ok {
+		return
+	}
+
+	offset, size := upload.PartRange(partNumber)
+	if r.ContentLength >= 0 && r.ContentLength != size {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("part %d must be %d bytes, got Content-Length %d", partNumber, size, r.ContentLength))
+		return
+	}
+
+	defer a.trackStreaming(part)()
+
+	// Read at most one byte past the expected size so oversized bodies are
+	// detected without storing them.
+	hash := sha256.New()
+	body := io.TeeReader(io.LimitReader(r.Body, size+1), hash)
+	written, err := a.bucket.UploadFrom(ctx, part.ObjectKey, body, &storage.UploadOptions{
+		ContentType: partContentType,
+		Metadata: map[string]string{
+			"upload_id":   id.String(),
+			"part_number": strconv.Itoa(partNumber),
+		},
+	})
+	metrics.TransferredBytes.WithLabelValues(metrics.DirectionUpload).Add(float64(written))
+	if err != nil {
+		failPart(ctx, part)
+		writeInternalError(w, r, err)
+		return
+	}
+	if written != size {
+		a.discardPart(ctx, part)
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("part %d must be %d bytes, got %d", partNumber, size, written))
+		return
+	}
+	digest := hash.Sum(nil)
+	if expectedDigest != nil && !bytes.Equal(digest, expectedDigest) {
+		a.discardPart(ctx, part)
+		writeError(w, http.StatusBadRequest, "Content-Digest does not match the received data")
+		return
+	}
+
+	markPartUploaded(w, r, part, offset, size, digest)
+}
+
+// getPendingPart loads a part that can still be uploaded, answering 404 for
+// unknown uploads and parts and uploads claimed by the garbage collector, and
+// 409 for completed uploads.
+func getPendingPart(w http.ResponseWriter, r *http.Request, id uuid.UUID, partNumber int) (*db.Upload, db.Part, bool) {
+	ctx := r.Context()
+	owner := requestOwner(r)
+	upload, err := db.GetUpload(ctx, owner, id)
+	if err != nil {
+		writeDBError(w, r, err)
+		return nil, db.Part{}, false
+	}
+	if upload.Status == db.UploadStatusFailed {
+		// The garbage collector is deleting the upload
+		writeDBError(w, r, db.ErrUploadNotFound{UploadID: id})
+		return nil, db.Part{}, false
+	}
+	if upload.Status == db.UploadStatusCompleted {
+		writeError(w, http.StatusConflict, "upload is already completed")
+		return nil, db.Part{}, false
+	}
+	parts, err := db.GetUploadParts(ctx, owner, id)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return nil, db.Part{}, false
+	}
+	part, ok := findPart(parts, partNumber)
+	if !ok {
+		writeDBError(w, r, db.ErrPartNotFound{UploadID: id, PartNumber: partNumber})
+		return nil, db.Part{}, false
+	}
+	return upload, part, true
+}
+
+// markPartUploaded records a verified part and responds with its status.
+func markPartUploaded(w http.ResponseWriter, r *http.Request, part db.Part, offset int64, size int64, digest []byte) {
+	ctx := r.Context()
+	part.Status = db.PartStatusUploaded
+	part.ByteOffset = &offset
+	part.ByteSize = &size
+	part.Sha256 = &digest
+	if err := db.UpdateUploadPart(ctx, part); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	upload, err := db.GetUpload(ctx, requestOwner(r), part.UploadID)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, uploadPartResponse{
+		UploadID:     part.UploadID,
+		PartNumber:   part.PartNumber,
+		Status:       part.Status,
+		ByteOffset:   offset,
+		ByteSize:     size,
+		Sha256:       hex.EncodeToString(digest),
+		UploadStatus: upload.Status,
+	})
+}
+
+// discardPart deletes a part object that failed verification and marks the
+// part as failed.
+func (a *API) discardPart(ctx context.Context, part db.Part) {
+	ctx = context.WithoutCancel(ctx)
+	if err := a.bucket.Delete(ctx, part.ObjectKey); err != nil {
+		logging.FromContext(ctx).Error("Failed to delete rejected part", "upload_id", part.UploadID, "part_number", part.PartNumber, "error", err)
+	}
+	failPart(ctx, part)
+}
+
+// failPart marks a part as failed. It runs even if the client has gone away.
+func failPart(ctx context.Context, part db.Part) {
+	ctx = context.WithoutCancel(ctx)
+	metrics.PartUploadFailures.Inc()
+	part.Status = db.PartStatusFailed
+	part.ByteOffset = nil
+	part.ByteSize = nil
+	part.Sha256 = nil
+	if err := db.UpdateUploadPart(ctx, part); err != nil {
+		logging.FromContext(ctx).Error("Failed to mark part as failed", "upload_id", part.UploadID, "part_number", part.PartNumber, "error", err)
+	}
+}
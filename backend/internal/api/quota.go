@@ -0,0 +1,75 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+)
+
+type quotaLimits struct {
+	MaxTotalBytes        *int64 `json:"max_total_bytes"`
+	MaxFileSize          *int64 `json:"max_file_size"`
+	MaxConcurrentUploads *int   `json:"max_concurrent_uploads"`
+}
+
+type quotaUsage struct {
+	TotalBytes        int64 `json:"total_bytes"`
+	UploadCount       int   `json:"upload_count"`
+	ConcurrentUploads int   `json:"concurrent_uploads"`
+}
+
+type quotaResponse struct {
+	Usage quotaUsage  `json:"usage"`
+	Quota quotaLimits `json:"quota"`
+	// RemainingBytes is omitted when there is no limit on total bytes
+	RemainingBytes *int64 `json:"remaining_bytes,omitempty"`
+}
+
+func newQuotaResponse(usage *db.Usage, quota *db.Quota) quotaResponse {
+	resp := quotaResponse{
+		Usage: quotaUsage{
+			TotalBytes:        usage.TotalBytes,
+			UploadCount:       usage.UploadCount,
+			ConcurrentUploads: usage.ConcurrentUploads,
+		},
+		Quota: quotaLimits{
+			MaxTotalBytes:        quota.MaxTotalBytes,
+			MaxFileSize:          quota.MaxFileSize,
+			MaxConcurrentUploads: quota.MaxConcurrentUploads,
+		},
+	}
+	if quota.MaxTotalBytes != nil {
+		remaining := max(*quota.MaxTotalBytes-usage.TotalBytes, 0)
+		resp.RemainingBytes = &remaining
+	}
+	return resp
+}
+
+// quotaExceededStatus is the status for a request refused by a quota. A file
+// that is too large can never be uploaded, whereas the other limits only hold
+// until the user frees up space or finishes an upload.
+func quotaExceededStatus(err db.ErrQuotaExceeded) int {
+	if err.Limit == db.QuotaLimitFileSize {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusForbidden
+}
+
+// GetQuota handles GET /quota, reporting the caller's usage against their
+// quota.
+func (a *API) GetQuota(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	owner := requestOwner(r)
+	usage, err := db.GetUsage(ctx, owner)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+	quota, err := db.GetQuota(ctx, owner)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, newQuotaResponse(usage, quota))
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+)
+
+func TestNewQuotaResponse(t *testing.T) {
+	t.Parallel()
+	maxTotalBytes := int64(100)
+	resp := newQuotaResponse(&db.Usage{TotalBytes: 30, UploadCount: 2, ConcurrentUploads: 1}, &db.Quota{MaxTotalBytes: &maxTotalBytes})
+	if resp.RemainingBytes == nil || *resp.RemainingBytes != 70 {
+		t.Fatalf("Expected 70 remaining bytes, got %v", resp.RemainingBytes)
+	}
+
+	resp = newQuotaResponse(&db.Usage{TotalBytes: 130}, &db.Quota{MaxTotalBytes: &maxTotalBytes})
+	if resp.RemainingBytes == nil || *resp.RemainingBytes != 0 {
+		t.Fatalf("Expected 0 remaining bytes over quota, got %v", resp.RemainingBytes)
+	}
+
+	resp = newQuotaResponse(&db.Usage{TotalBytes: 130}, &db.Quota{})
+	if resp.RemainingBytes != nil {
+		t.Fatalf("Expected no remaining bytes without a limit, got %d", *resp.RemainingBytes)
+	}
+}
+
+func TestQuotaExceededStatus(t *testing.T) {
+	t.Parallel()
+	if status := quotaExceededStatus(db.ErrQuotaExceeded{Limit: db.QuotaLimitFileSize}); status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected status 413 for file size, got %d", status)
+	}
+	if status := quotaExceededStatus(db.ErrQuotaExceeded{Limit: db.QuotaLimitTotalBytes}); status != http.StatusForbidden {
+		t.Fatalf("Expected status 403 for total bytes, got %d", status)
+	}
+}
@@ -0,0 +1,206 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type createShareRequest struct {
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	MaxDownloads *int       `json:"max_downloads,omitempty"`
+	Password     string     `json:"password,omitempty"`
+}
+
+type shareResponse struct {
+	ID           uuid.UUID  `json:"id"`
+	UploadID     uuid.UUID  `json:"upload_id"`
+	Token        string     `json:"token"`
+	URL          string     `json:"url"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	MaxDownloads *int       `json:"max_downloads,omitempty"`
+	Protected    bool       `json:"password_protected"`
+}
+
+func (req createShareRequest) validate(now time.Time) error {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return errors.New("expires_at must be in the future")
+	}
+	if req.MaxDownloads != nil && *req.MaxDownloads <= 0 {
+		return errors.New("max_downloads must be positive")
+	}
+	if len(req.Password) > 72 {
+		return errors.New("password must be at most 72 bytes")
+	}
+	return nil
+}
+
+// newShareToken returns an unguessable token for a share link, and the hash
+// under which it is stored.
+func newShareToken() (string, []byte) {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	token := base64.RawURLEncoding.EncodeToString(secret)
+	return token, hashShareToken(token)
+}
+
+func hashShareToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// CreateShare handles POST /uploads/{id}/shares, creating a download link for
+// a finalized upload. The token is only ever returned in this response.
+func (a *API) CreateShare(w http.ResponseWriter, r *http.Request) {
+	uploadID, err := uploadIDFromPath(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var req createShareRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := req.validate(time.Now()); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	_, err = db.GetUpload(ctx, requestOwner(r), uploadID)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	share := db.Share{
+		ID:           uuid.New(),
+		UploadID:     uploadID,
+		ExpiresAt:    req.ExpiresAt,
+		MaxDownloads: req.MaxDownloads,
+	}
+	if req.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			writeInternalError(w, r, err)
+			return
+		}
+		passwordHash := string(hash)
+		share.PasswordHash = &passwordHash
+	}
+	token, tokenHash := newShareToken()
+
+	err = db.CreateShare(ctx, share, tokenHash)
+	var notFinalized db.ErrUploadNotFinalized
+	if errors.As(err, &notFinalized) {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/s/"+token)
+	writeJSON(w, http.StatusCreated, shareResponse{
+		ID:           share.ID,
+		UploadID:     uploadID,
+		Token:        token,
+		URL:          "/s/" + token,
+		ExpiresAt:    share.ExpiresAt,
+		MaxDownloads: share.MaxDownloads,
+		Protected:    share.PasswordHash != nil,
+	})
+}
+
+// downloadClaimer uses up a download of a share once the response turns out to
+// carry file content, that is when http.ServeContent answers a GET with 200
+// or 206, so that every such response counts and 304 and 416 responses do
+// not. If no download is left, it answers 410 instead and drops the content.
+type downloadClaimer struct {
+	http.ResponseWriter
+	r *http.Request
+	// claim uses up a download, failing with db.ErrShareUnavailable if none
+	// is left
+	claim       func() error
+	wroteHeader bool
+	// err is why the download could not be claimed
+	err error
+}
+
+func (c *downloadClaimer) WriteHeader(status int) {
+	if c.wroteHeader {
+		return
+	}
+	c.wroteHeader = true
+	if c.r.Method != http.MethodGet || (status != http.StatusOK && status != http.StatusPartialContent) {
+		c.ResponseWriter.WriteHeader(status)
+		return
+	}
+	c.err = c.claim()
+	if c.err == nil {
+		c.ResponseWriter.WriteHeader(status)
+		return
+	}
+	// Drop the headers describing the content that is no longer sent
+	for _, name := range []string{"Content-Length", "Content-Range", "Content-Disposition", "ETag", "Last-Modified"} {
+		c.ResponseWriter.Header().Del(name)
+	}
+	var unavailable db.ErrShareUnavailable
+	if errors.As(c.err, &unavailable) {
+		writeError(c.ResponseWriter, http.StatusGone, "share has expired or reached its download limit")
+	} else {
+		writeInternalError(c.ResponseWriter, c.r, c.err)
+	}
+}
+
+func (c *downloadClaimer) Write(p []byte) (int, error) {
+	if !c.wroteHeader {
+		c.WriteHeader(http.StatusOK)
+	}
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.ResponseWriter.Write(p)
+}
+
+// DownloadShare handles GET /s/{token}. Password protected shares take the
+// password through HTTP basic authentication, with any user name.
+func (a *API) DownloadShare(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	ctx := r.Context()
+	share, err := db.GetShareByTokenHash(ctx, hashShareToken(r.PathValue("token")))
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	if !share.Available(time.Now()) {
+		writeError(w, http.StatusGone, "share has expired or reached its download limit")
+		return
+	}
+	if share.PasswordHash != nil {
+		_, password, _ := r.BasicAuth()
+		if bcrypt.CompareHashAndPassword([]byte(*share.PasswordHash), []byte(password)) != nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="share", charset="UTF-8"`)
+			writeError(w, http.StatusUnauthorized, "a valid password is required")
+			return
+		}
+	}
+
+	upload, err := db.GetSharedUpload(ctx, share.ID)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+	claimer := &downloadClaimer{ResponseWriter: w, r: r, claim: func() error {
+		return db.ClaimShareDownload(ctx, share.ID)
+	}}
+	a.serveUpload(claimer, r, upload)
+}
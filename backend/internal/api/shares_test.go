@@ -0,0 +1,109 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/problem"
+)
+
+func TestCreateShareRequestValidate(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	future := now.Add(time.Hour)
+	past := now.Add(-time.Hour)
+	maxDownloads := 3
+	zero := 0
+
+	err := createShareRequest{ExpiresAt: &future, MaxDownloads: &maxDownloads, Password: "secret"}.validate(now)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	invalid := map[string]createShareRequest{
+		"expired":           {ExpiresAt: &past},
+		"zero downloads":    {MaxDownloads: &zero},
+		"too long password": {Password: string(make([]byte, 73))},
+	}
+	for name, req := range invalid {
+		if err := req.validate(now); err == nil {
+			t.Fatalf("Expected error for %s, got none", name)
+		}
+	}
+}
+
+func TestNewShareToken(t *testing.T) {
+	t.Parallel()
+	token, tokenHash := newShareToken()
+	other, _ := newShareToken()
+	if len(token) != 43 {
+		t.Fatalf("Expected 43 character token, got %d", len(token))
+	}
+	if token == other {
+		t.Fatalf("Expected tokens to be unique")
+	}
+	if string(hashShareToken(token)) != string(tokenHash) {
+		t.Fatalf("Expected token hash to match")
+	}
+}
+
+func TestDownloadClaimer(t *testing.T) {
+	t.Parallel()
+	a := newTestAPI()
+	upload := newTestUpload(t, a, "0123456789")
+	etag := uploadETag(upload)
+	cases := []struct {
+		method  string
+		headers map[string]string
+		status  int
+		claimed bool
+	}{
+		{http.MethodGet, nil, http.StatusOK, true},
+		{http.MethodGet, map[string]string{"Range": "bytes=0-1"}, http.StatusPartialContent, true},
+		{http.MethodGet, map[string]string{"Range": "bytes=-1"}, http.StatusPartialContent, true},
+		{http.MethodGet, map[string]string{"Range": "bytes=20-"}, http.StatusRequestedRangeNotSatisfiable, false},
+		{http.MethodGet, map[string]string{"If-None-Match": etag}, http.StatusNotModified, false},
+		{http.MethodHead, nil, http.StatusOK, false},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(c.method, "/s/token", nil)
+		for name, value := range c.headers {
+			r.Header.Set(name, value)
+		}
+		claims := 0
+		w := httptest.NewRecorder()
+		a.serveUpload(&downloadClaimer{ResponseWriter: w, r: r, claim: func() error {
+			claims++
+			return nil
+		}}, r, upload)
+		if w.Code != c.status {
+			t.Fatalf("Expected status %d for %s %v, got %d", c.status, c.method, c.headers, w.Code)
+		}
+		if claimed := claims == 1; claimed != c.claimed || claims > 1 {
+			t.Fatalf("Expected claimed %v for %s %v, got %d claims", c.claimed, c.method, c.headers, claims)
+		}
+	}
+}
+
+func TestDownloadClaimer_Unavailable(t *testing.T) {
+	t.Parallel()
+	a := newTestAPI()
+	upload := newTestUpload(t, a, "0123456789")
+	r := httptest.NewRequest(http.MethodGet, "/s/token", nil)
+	w := httptest.NewRecorder()
+	a.serveUpload(&downloadClaimer{ResponseWriter: w, r: r, claim: func() error {
+		return db.ErrShareUnavailable{}
+	}}, r, upload)
+	if w.Code != http.StatusGone {
+		t.Fatalf("Expected status 410, got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "0123456789") {
+		t.Fatalf("Expected no file content, got %q", w.Body.String())
+	}
+	if w.Header().Get("Content-Type") != problem.ContentType {
+		t.Fatalf("Expected Content-Type %s, got %q", problem.ContentType, w.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/metrics"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/storage"
+	"github.com/google/uuid"
+)
+
+const partContentType = "application/octet-stream"
+
+type partUploadURLResponse struct {
+	UploadID   uuid.UUID `json:"upload_id"`
+	PartNumber int       `json:"part_number"`
+	ByteOffset int64     `json:"byte_offset"`
+	ByteSize   int64     `json:"byte_size"`
+	storage.SignedUpload
+}
+
+// CreatePartUploadURL handles POST /uploads/{id}/parts/{n}/upload-url. It
+// returns a signed URL with which the client uploads the part straight to the
+// bucket, after which it calls ConfirmPartUpload.
+func (a *API) CreatePartUploadURL(w http.ResponseWriter, r *http.Request) {
+	id, err := uploadIDFromPath(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	partNumber, err := partNumberFromPath(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	upload, part, ok := getPendingPart(w, r, id, partNumber)
+	if !ok {
+		return
+	}
+
+	offset, size := upload.PartRange(partNumber)
+	signed, err := a.bucket.SignedUploadURL(r.Context(), part.ObjectKey, storage.SignedUploadOptions{
+		ContentType:   partContentType,
+		ContentLength: size,
+		Expires:       a.config.SignedURLTTL,
+	})
+	if errors.Is(err, storage.ErrSigningNotSupported) {
+		writeError(w, http.StatusNotImplemented, "signed upload URLs are not supported by this storage backend")
+		return
+	} else if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, partUploadURLResponse{
+		UploadID:     id,
+		PartNumber:   partNumber,
+		ByteOffset:   offset,
+		ByteSize:     size,
+		SignedUpload: *signed,
+	})
+}
+
+// ConfirmPartUpload handles POST /uploads/{id}/parts/{n}/confirm, called once
+// a part has been uploaded with a signed URL. The object is checked to exist
+// with the part's size and read back for its SHA-256, which must match a
+// sha-256 Content-Digest header if one is given.
+func (a *API) ConfirmPartUpload(w http.ResponseWriter, r *http.Request) {
+	id, err := uploadIDFromPath(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	partNumber, err := partNumberFromPath(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	expectedDigest, err := parseContentDigest(r.Header)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	upload, part, ok := getPendingPart(w, r, id, partNumber)
+	if !ok {
+		return
+	}
+	offset, size := upload.PartRange(partNumber)
+
+	attrs, err := a.bucket.Attrs(ctx, part.ObjectKey)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		writeError(w, http.StatusConflict, fmt.Sprintf("part %d has not been uploaded", partNumber))
+		return
+	} else if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+	if attrs.Size != size {
+		a.discardPart(ctx, part)
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("part %d must be %d bytes, got %d", partNumber, size, attrs.Size))
+		return
+	}
+	digest, err := a.hashObject(ctx, part.ObjectKey)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+	if expectedDigest != nil && !bytes.Equal(digest, expectedDigest) {
+		a.discardPart(ctx, part)
+		writeError(w, http.StatusBadRequest, "Content-Digest does not match the uploaded data")
+		return
+	}
+
+	markPartUploaded(w, r, part, offset, size, digest)
+}
+
+// PutSignedObject handles PUT /storage/{key}, the target of URLs signed by
+// storage.HMACSigner when the bucket cannot sign URLs itself.
+func (a *API) PutSignedObject(w http.ResponseWriter, r *http.Request) {
+	signer, ok := a.bucket.Store().(*storage.HMACSigner)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	objectName := r.PathValue("key")
+	contentType := r.Header.Get("Content-Type")
+	length, err := signer.VerifyUpload(objectName, r.URL.Query(), contentType, time.Now())
+	if err != nil {
+		writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	if r.ContentLength >= 0 && r.ContentLength != length {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("body must be %d bytes, got Content-Length %d", length, r.ContentLength))
+		return
+	}
+
+	ctx := r.Context()
+	written, err := signer.UploadFrom(ctx, objectName, io.LimitReader(r.Body, length+1), &storage.UploadOptions{ContentType: contentType})
+	metrics.TransferredBytes.WithLabelValues(metrics.DirectionUpload).Add(float64(written))
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+	if written != length {
+		if err := signer.Delete(ctx, objectName); err != nil {
+			writeInternalError(w, r, err)
+			return
+		}
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("body must be %d bytes, got %d", length, written))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// hashObject reads back an object uploaded with a signed URL to compute its
+// SHA-256, which the bucket does not report.
+func (a *API) hashObject(ctx context.Context, objectKey string) ([]byte, error) {
+	reader, err := a.bucket.NewRangeReader(ctx, objectKey, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, reader); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/storage"
+)
+
+func TestPutSignedObject(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	signer := storage.NewHMACSigner(storage.NewMemoryStore(), []byte("key"), "/storage")
+	a := New(storage.NewBucket(signer), DefaultConfig())
+
+	signed, err := signer.SignedUploadURL(ctx, "upload-1-0", storage.SignedUploadOptions{
+		ContentType:   partContentType,
+		ContentLength: 5,
+		Expires:       time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Failed to sign URL: %v", err)
+	}
+
+	put := func(target string, body string) *httptest.ResponseRecorder {
+		u, err := url.Parse(target)
+		if err != nil {
+			t.Fatalf("Failed to parse URL: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPut, target, strings.NewReader(body))
+		req.SetPathValue("key", strings.TrimPrefix(u.Path, "/storage/"))
+		req.Header.Set("Content-Type", partContentType)
+		w := httptest.NewRecorder()
+		a.PutSignedObject(w, req)
+		return w
+	}
+
+	if w := put(signed.URL, "hello!"); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400 for a body of the wrong size, got %d", w.Code)
+	}
+	if w := put(strings.Replace(signed.URL, "upload-1-0", "upload-1-1", 1), "hello"); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403 for another object, got %d", w.Code)
+	}
+	if exists, _ := signer.Exists(ctx, "upload-1-1"); exists {
+		t.Fatalf("Expected no object to be written for a bad signature")
+	}
+	if w := put(signed.URL, "hello"); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	data, err := signer.Download(ctx, "upload-1-0")
+	if err != nil {
+		t.Fatalf("Failed to download object: %v", err)
+	}
+	if string(data) != "hello" {
+		t.Fatalf("Expected hello, got %s", string(data))
+	}
+}
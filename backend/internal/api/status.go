@@ -0,0 +1,111 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+	"github.com/google/uuid"
+)
+
+type partStatusResponse struct {
+	PartNumber int           `json:"part_number"`
+	Status     db.PartStatus `json:"status"`
+	ObjectKey  string        `json:"object_key"`
+	ByteOffset int64         `json:"byte_offset"`
+	ByteSize   int64         `json:"byte_size"`
+	UploadedAt *time.Time    `json:"uploaded_at,omitempty"`
+}
+
+type uploadStatusResponse struct {
+	ID              uuid.UUID            `json:"id"`
+	Status          db.UploadStatus      `json:"status"`
+	Size            int                  `json:"size"`
+	MimeType        string               `json:"mime_type"`
+	PartsCount      int                  `json:"parts_count"`
+	CreatedAt       time.Time            `json:"created_at"`
+	Finalized       bool                 `json:"finalized"`
+	ObjectKey       *string              `json:"object_key,omitempty"`
+	Sha256          *string              `json:"sha256,omitempty"`
+	CompositeSha256 *string              `json:"composite_sha256,omitempty"`
+	FinalizedAt     *time.Time           `json:"finalized_at,omitempty"`
+	BytesReceived   int64                `json:"bytes_received"`
+	PercentComplete float64              `json:"percent_complete"`
+	PendingParts    []int                `json:"pending_parts"`
+	UploadedParts   []int                `json:"uploaded_parts"`
+	FailedParts     []int                `json:"failed_parts"`
+	Parts           []partStatusResponse `json:"parts"`
+}
+
+func newUploadStatusResponse(upload *db.Upload, parts []db.Part) uploadStatusResponse {
+	resp := uploadStatusResponse{
+		ID:            upload.ID,
+		Status:        upload.Status,
+		Size:          upload.Size,
+		MimeType:      upload.MimeType,
+		PartsCount:    upload.PartsCount,
+		CreatedAt:     upload.CreatedAt,
+		Finalized:     upload.Finalized(),
+		ObjectKey:     upload.ObjectKey,
+		FinalizedAt:   upload.FinalizedAt,
+		PendingParts:  []int{},
+		UploadedParts: []int{},
+		FailedParts:   []int{},
+		Parts:         make([]partStatusResponse, len(parts)),
+	}
+	resp.Sha256, resp.CompositeSha256 = uploadDigests(upload)
+
+	for i, part := range parts {
+		offset, size := upload.PartRange(part.PartNumber)
+		resp.Parts[i] = partStatusResponse{
+			PartNumber: part.PartNumber,
+			Status:     part.Status,
+			ObjectKey:  part.ObjectKey,
+			ByteOffset: offset,
+			ByteSize:   size,
+			UploadedAt: part.UploadedAt,
+		}
+		switch part.Status {
+		case db.PartStatusPending:
+			resp.PendingParts = append(resp.PendingParts, part.PartNumber)
+		case db.PartStatusUploaded:
+			resp.UploadedParts = append(resp.UploadedParts, part.PartNumber)
+			if part.ByteSize != nil {
+				resp.BytesReceived += *part.ByteSize
+			}
+		case db.PartStatusFailed:
+			resp.FailedParts = append(resp.FailedParts, part.PartNumber)
+		}
+	}
+	if upload.Size > 0 {
+		resp.PercentComplete = float64(resp.BytesReceived) * 100 / float64(upload.Size)
+	}
+	return resp
+}
+
+// GetUploadStatus handles GET /uploads/{id}. Besides the upload itself it
+// reports which parts are still pending or have failed, so that an interrupted
+// transfer can be resumed by sending only those parts.
+func (a *API) GetUploadStatus(w http.ResponseWriter, r *http.Request) {
+	id, err := uploadIDFromPath(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	owner := requestOwner(r)
+	upload, err := db.GetUpload(ctx, owner, id)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	parts, err := db.GetUploadParts(ctx, owner, id)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, newUploadStatusResponse(upload, parts))
+}
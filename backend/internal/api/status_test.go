@@ -0,0 +1,45 @@
+package api
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+	"github.com/google/uuid"
+)
+
+func TestNewUploadStatusResponse(t *testing.T) {
+	t.Parallel()
+	id := uuid.New()
+	upload := &db.Upload{ID: id, PartsCount: 4, Size: 100, Status: db.UploadStatusInProgress}
+	byteSize := int64(25)
+	parts := []db.Part{
+		{UploadID: id, PartNumber: 0, Status: db.PartStatusUploaded, ByteSize: &byteSize},
+		{UploadID: id, PartNumber: 1, Status: db.PartStatusFailed},
+		{UploadID: id, PartNumber: 2, Status: db.PartStatusUploaded, ByteSize: &byteSize},
+		{UploadID: id, PartNumber: 3, Status: db.PartStatusPending},
+	}
+
+	resp := newUploadStatusResponse(upload, parts)
+	if resp.BytesReceived != 50 {
+		t.Fatalf("Expected 50 bytes received, got %d", resp.BytesReceived)
+	}
+	if resp.PercentComplete != 50 {
+		t.Fatalf("Expected 50 percent complete, got %f", resp.PercentComplete)
+	}
+	if !slices.Equal(resp.UploadedParts, []int{0, 2}) {
+		t.Fatalf("Expected uploaded parts [0 2], got %v", resp.UploadedParts)
+	}
+	if !slices.Equal(resp.FailedParts, []int{1}) {
+		t.Fatalf("Expected failed parts [1], got %v", resp.FailedParts)
+	}
+	if !slices.Equal(resp.PendingParts, []int{3}) {
+		t.Fatalf("Expected pending parts [3], got %v", resp.PendingParts)
+	}
+	if resp.Parts[3].ByteOffset != 75 || resp.Parts[3].ByteSize != 25 {
+		t.Fatalf("Expected part 3 to cover bytes 75-99, got offset %d size %d", resp.Parts[3].ByteOffset, resp.Parts[3].ByteSize)
+	}
+	if resp.Finalized || resp.Sha256 != nil {
+		t.Fatalf("Expected upload not to be finalized")
+	}
+}
@@ -0,0 +1,475 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/logging"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/metrics"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/storage"
+	"github.com/google/uuid"
+)
+
+// The handlers below implement version 1.0.0 of the tus resumable upload
+// protocol (https://tus.io/protocols/resumable-upload) with the creation,
+// termination, checksum and expiration extensions. A tus upload is a single
+// part upload. The body of every PATCH request is stored as one or more
+// segment objects, and once the last byte arrives the segments are composed
+// into the final object and the upload is finalized.
+
+const (
+	TusVersion    = "1.0.0"
+	tusExtensions = "creation,termination,checksum,expiration"
+
+	tusContentType         = "application/offset+octet-stream"
+	statusChecksumMismatch = 460
+)
+
+var tusChecksumAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+}
+
+// checkTusResumable rejects requests for protocol versions other than ours.
+func checkTusResumable(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Set("Tus-Resumable", TusVersion)
+	if r.Header.Get("Tus-Resumable") != TusVersion {
+		w.Header().Set("Tus-Version", TusVersion)
+		writeError(w, http.StatusPreconditionFailed, "unsupported tus version")
+		return false
+	}
+	return true
+}
+
+// TusOptions handles OPTIONS /tus/ and /tus/{id}, advertising the protocol
+// version and extensions supported.
+func (a *API) TusOptions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", TusVersion)
+	w.Header().Set("Tus-Version", TusVersion)
+	w.Header().Set("Tus-Extension", tusExtensions)
+	w.Header().Set("Tus-Max-Size", strconv.FormatInt(a.config.TusMaxSize, 10))
+	w.Header().Set("Tus-Checksum-Algorithm", "md5,sha1,sha256")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseTusMetadata validates an Upload-Metadata header, a comma separated list
+// of keys each optionally followed by a space and a base64 encoded value.
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("Upload-Metadata has an empty key")
+		}
+		if _, ok := metadata[key]; ok {
+			return nil, fmt.Errorf("Upload-Metadata key %q is repeated", key)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("Upload-Metadata value for %q is not base64", key)
+		}
+		metadata[key] = string(decoded)
+	}
+	return metadata, nil
+}
+
+// tusMimeType picks the MIME type from the metadata keys used by tus clients.
+func tusMimeType(metadata map[string]string) string {
+	for _, key := range []string{"filetype", "type", "contentType"} {
+		if _, _, err := mime.ParseMediaType(metadata[key]); err == nil {
+			return metadata[key]
+		}
+	}
+	return "application/octet-stream"
+}
+
+// TusCreate handles POST /tus/ (creation extension).
+func (a *API) TusCreate(w http.ResponseWriter, r *http.Request) {
+	if !checkTusResumable(w, r) {
+		return
+	}
+	if r.Header.Get("Upload-Defer-Length") != "" {
+		writeError(w, http.StatusBadRequest, "Upload-Defer-Length is not supported")
+		return
+	}
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		writeError(w, http.StatusBadRequest, "Upload-Length must be a positive integer")
+		return
+	}
+	if length > a.config.TusMaxSize {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Upload-Length exceeds Tus-Max-Size of %d", a.config.TusMaxSize))
+		return
+	}
+	rawMetadata := r.Header.Get("Upload-Metadata")
+	metadata, err := parseTusMetadata(rawMetadata)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id := uuid.New()
+	expiresAt := time.Now().Add(a.config.TusUploadTTL)
+	err = db.CreateTusUpload(r.Context(), requestOwner(r), id, int(length), tusMimeType(metadata), rawMetadata, expiresAt)
+	var quotaExceeded db.ErrQuotaExceeded
+	if errors.As(err, &quotaExceeded) {
+		writeError(w, quotaExceededStatus(quotaExceeded), err.Error())
+		return
+	} else if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/tus/"+id.String())
+	w.Header().Set("Upload-Expires", expiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// getTusUpload loads the upload named in the path, answering 404 for unknown
+// uploads and uploads claimed by the garbage collector, and 410 for expired
+// ones.
+func getTusUpload(w http.ResponseWriter, r *http.Request) (*db.TusUpload, bool) {
+	id, err := uploadIDFromPath(r)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return nil, false
+	}
+	upload, err := db.GetTusUpload(r.Context(), requestOwner(r), id)
+	if err != nil {
+		writeDBError(w, r, err)
+		return nil, false
+	}
+	if upload.Status == db.UploadStatusFailed {
+		// The garbage collector is deleting the upload
+		writeDBError(w, r, db.ErrUploadNotFound{UploadID: id})
+		return nil, false
+	}
+	if !upload.Finalized && time.Now().After(upload.ExpiresAt) {
+		writeError(w, http.StatusGone, "upload has expired")
+		return nil, false
+	}
+	return upload, true
+}
+
+func setTusOffsetHeaders(w http.ResponseWriter, upload *db.TusUpload) {
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	if !upload.Finalized {
+		w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+	w.Header().Set("Cache-Control", "no-store")
+}
+
+// TusHead handles HEAD /tus/{id}, reporting how many bytes have been received.
+func (a *API) TusHead(w http.ResponseWriter, r *http.Request) {
+	if !checkTusResumable(w, r) {
+		return
+	}
+	upload, ok := getTusUpload(w, r)
+	if !ok {
+		return
+	}
+	setTusOffsetHeaders(w, upload)
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	if upload.Metadata != "" {
+		w.Header().Set("Upload-Metadata", upload.Metadata)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+type tusChecksum struct {
+	hash     hash.Hash
+	expected []byte
+}
+
+// parseUploadChecksum parses an Upload-Checksum header such as
+// `sha1 Kq5sNclPz7QV2+lfQIuc6R7oRu0=`. It returns nil if the header is absent.
+func parseUploadChecksum(header http.Header) (*tusChecksum, error) {
+	value := header.Get("Upload-Checksum")
+	if value == "" {
+		return nil, nil
+	}
+	algorithm, encoded, ok := strings.Cut(value, " ")
+	if !ok {
+		return nil, errors.New("malformed Upload-Checksum header")
+	}
+	newHash, ok := tusChecksumAlgorithms[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unsupported checksum algorithm: %q", algorithm)
+	}
+	expected, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("malformed Upload-Checksum header")
+	}
+	return &tusChecksum{hash: newHash(), expected: expected}, nil
+}
+
+// TusPatch handles PATCH /tus/{id}, appending the body at Upload-Offset.
+func (a *API) TusPatch(w http.ResponseWriter, r *http.Request) {
+	if !checkTusResumable(w, r) {
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != tusContentType {
+		writeError(w, http.StatusUnsupportedMediaType, "Content-Type must be "+tusContentType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		writeError(w, http.StatusBadRequest, "Upload-Offset must be a non-negative integer")
+		return
+	}
+	checksum, err := parseUploadChecksum(r.Header)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	upload, ok := getTusUpload(w, r)
+	if !ok {
+		return
+	}
+	if offset != upload.Offset {
+		writeError(w, http.StatusConflict, fmt.Sprintf("Upload-Offset %d does not match the current offset %d", offset, upload.Offset))
+		return
+	}
+
+	ctx := r.Context()
+	if upload.Offset < upload.Length {
+		status, err := a.appendTusBody(ctx, upload, r.Body, checksum)
+		if status == http.StatusInternalServerError {
+			writeInternalError(w, r, err)
+			return
+		} else if err != nil {
+			writeError(w, status, err.Error())
+			return
+		}
+	}
+	if upload.Offset == upload.Length && !upload.Finalized {
+		if err := a.finishTusUpload(ctx, upload); err != nil {
+			writeInternalError(w, r, err)
+			return
+		}
+		upload.Finalized = true
+	}
+
+	setTusOffsetHeaders(w, upload)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// appendTusBody appends body to the upload in segments of at most
+// TusSegmentSize bytes, advancing the offset past each one stored, so that if
+// the body is cut off the client can resume after the last stored segment. A
+// body with an Upload-Checksum is stored as a single segment, since it has to
+// be rejected as a whole if it does not match. On failure it returns the
+// status code to answer with.
+func (a *API) appendTusBody(ctx context.Context, upload *db.TusUpload, body io.Reader, checksum *tusChecksum) (int, error) {
+	fileHash := sha256.New()
+	if upload.HashState != nil {
+		if err := fileHash.(encoding.BinaryUnmarshaler).UnmarshalBinary(upload.HashState); err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+	for {
+		// Reading a byte more than remains tells that the body is too large
+		limit := upload.Length - upload.Offset + 1
+		if checksum == nil && a.config.TusSegmentSize < limit {
+			limit = a.config.TusSegmentSize
+		}
+		written, status, err := a.appendTusSegment(ctx, upload, fileHash, io.LimitReader(body, limit), checksum)
+		if err != nil || written < limit || upload.Offset == upload.Length {
+			return status, err
+		}
+	}
+}
+
+// appendTusSegment stores body as a segment at the upload's offset and advances
+// the offset. fileHash holds the hash of the upload up to the offset and is
+// advanced along with it. It returns the number of bytes read from body and,
+// on failure, the status code to answer with.
+func (a *API) appendTusSegment(ctx context.Context, upload *db.TusUpload, fileHash hash.Hash, body io.Reader, checksum *tusChecksum) (int64, int, error) {
+	hashes := io.Writer(fileHash)
+	if checksum != nil {
+		hashes = io.MultiWriter(fileHash, checksum.hash)
+	}
+
+	suffix := make([]byte, 8)
+	rand.Read(suffix)
+	segment := db.TusSegment{
+		UploadID:   upload.UploadID,
+		ByteOffset: upload.Offset,
+		ObjectKey:  fmt.Sprintf("upload-%s-tus-%d-%s", upload.UploadID, upload.Offset, hex.EncodeToString(suffix)),
+	}
+	remaining := upload.Length - upload.Offset
+	written, err := a.bucket.UploadFrom(ctx, segment.ObjectKey, io.TeeReader(body, hashes), &storage.UploadOptions{
+		ContentType: "application/octet-stream",
+	})
+	metrics.TransferredBytes.WithLabelValues(metrics.DirectionUpload).Add(float64(written))
+	segment.ByteSize = written
+
+	var status int
+	switch {
+	case err != nil:
+		status = http.StatusInternalServerError
+	case written == 0:
+		status, err = http.StatusNoContent, nil
+	case written > remaining:
+		status, err = http.StatusRequestEntityTooLarge, fmt.Errorf("body exceeds the %d bytes remaining", remaining)
+	case checksum != nil && !bytes.Equal(checksum.hash.Sum(nil), checksum.expected):
+		status, err = statusChecksumMismatch, errors.New("checksum mismatch")
+	default:
+		var hashState []byte
+		hashState, err = fileHash.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			status = http.StatusInternalServerError
+			break
+		}
+		expiresAt := time.Now().Add(a.config.TusUploadTTL)
+		err = db.AppendTusSegment(ctx, segment, hashState, expiresAt)
+		var mismatch db.ErrUploadOffsetMismatch
+		var notFound db.ErrUploadNotFound
+		if errors.As(err, &mismatch) {
+			status = http.StatusConflict
+			break
+		} else if errors.As(err, &notFound) {
+			status = http.StatusNotFound
+			break
+		} else if err != nil {
+			status = http.StatusInternalServerError
+			break
+		}
+		upload.Offset += written
+		upload.HashState = hashState
+		upload.ExpiresAt = expiresAt
+		return written, http.StatusNoContent, nil
+	}
+
+	if err := a.bucket.Delete(context.WithoutCancel(ctx), segment.ObjectKey); err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+		logging.FromContext(ctx).Error("Failed to delete rejected tus segment", "upload_id", upload.UploadID, "object_key", segment.ObjectKey, "error", err)
+	}
+	return written, status, err
+}
+
+// finishTusUpload composes the segments of a fully received upload straight
+// into its final object and finalizes the upload with the SHA-256 kept in the
+// hash state, so that the data is neither copied nor read again.
+func (a *API) finishTusUpload(ctx context.Context, upload *db.TusUpload) error {
+	parts, err := db.GetUploadParts(ctx, upload.OwnerID, upload.UploadID)
+	if err != nil {
+		return err
+	}
+	part := parts[0]
+
+	segments, err := db.GetTusSegments(ctx, upload.UploadID)
+	if err != nil {
+		return err
+	}
+	fileHash := sha256.New()
+	if err := fileHash.(encoding.BinaryUnmarshaler).UnmarshalBinary(upload.HashState); err != nil {
+		return err
+	}
+	digest := fileHash.Sum(nil)
+	objectKey := db.FinalObjectKey(upload.UploadID)
+	// A previous request may have stopped after composing the segments
+	if part.Status != db.PartStatusUploaded {
+		objectKeys := make([]string, len(segments))
+		for i, segment := range segments {
+			objectKeys[i] = segment.ObjectKey
+		}
+		if err := a.bucket.ComposeAll(ctx, objectKey, objectKeys); err != nil {
+			return fmt.Errorf("composing segments: %w", err)
+		}
+		attrs, err := a.bucket.Attrs(ctx, objectKey)
+		if err != nil {
+			return err
+		}
+		if attrs.Size != upload.Length {
+			return fmt.Errorf("composed object is %d bytes, expected %d", attrs.Size, upload.Length)
+		}
+
+		offset := int64(0)
+		part.Status = db.PartStatusUploaded
+		part.ByteOffset = &offset
+		part.ByteSize = &upload.Length
+		part.Sha256 = &digest
+		if err := db.UpdateUploadPart(ctx, part); err != nil {
+			return err
+		}
+	}
+
+	err = db.FinalizeUpload(ctx, upload.UploadID, objectKey, upload.Length, digest)
+	var alreadyFinalized db.ErrUploadAlreadyFinalized
+	if err != nil && !errors.As(err, &alreadyFinalized) {
+		return err
+	}
+	for _, segment := range segments {
+		if err := a.bucket.Delete(ctx, segment.ObjectKey); err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+			logging.FromContext(ctx).Error("Failed to delete tus segment", "upload_id", upload.UploadID, "object_key", segment.ObjectKey, "error", err)
+		}
+	}
+	return nil
+}
+
+// TusDelete handles DELETE /tus/{id} (termination extension), deleting the
+// upload and everything stored for it.
+func (a *API) TusDelete(w http.ResponseWriter, r *http.Request) {
+	if !checkTusResumable(w, r) {
+		return
+	}
+	upload, ok := getTusUpload(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	segments, err := db.GetTusSegments(ctx, upload.UploadID)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+	parts, err := db.GetUploadParts(ctx, upload.OwnerID, upload.UploadID)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+	objectKeys := []string{db.FinalObjectKey(upload.UploadID)}
+	for _, segment := range segments {
+		objectKeys = append(objectKeys, segment.ObjectKey)
+	}
+	for _, part := range parts {
+		objectKeys = append(objectKeys, part.ObjectKey)
+	}
+	for _, key := range objectKeys {
+		if err := a.bucket.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+			writeInternalError(w, r, err)
+			return
+		}
+	}
+
+	err = db.DeleteUpload(ctx, upload.OwnerID, upload.UploadID)
+	var notFound db.ErrUploadNotFound
+	if err != nil && !errors.As(err, &notFound) {
+		writeInternalError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
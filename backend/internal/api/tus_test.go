@@ -0,0 +1,99 @@
+package api
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTusMetadata(t *testing.T) {
+	t.Parallel()
+	metadata, err := parseTusMetadata("filename d29ybGRfZG9taW5hdGlvbl9wbGFuLnBkZg==,filetype YXBwbGljYXRpb24vcGRm,is_confidential")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if metadata["filename"] != "world_domination_plan.pdf" {
+		t.Fatalf("Expected filename world_domination_plan.pdf, got %q", metadata["filename"])
+	}
+	if _, ok := metadata["is_confidential"]; !ok {
+		t.Fatalf("Expected key without value to be present")
+	}
+	if mimeType := tusMimeType(metadata); mimeType != "application/pdf" {
+		t.Fatalf("Expected mime type application/pdf, got %s", mimeType)
+	}
+	if mimeType := tusMimeType(map[string]string{}); mimeType != "application/octet-stream" {
+		t.Fatalf("Expected default mime type, got %s", mimeType)
+	}
+
+	for _, header := range []string{"filename not-base64!", "a YQ==,a YQ==", " ,a"} {
+		if _, err := parseTusMetadata(header); err == nil {
+			t.Fatalf("Expected error for %q, got none", header)
+		}
+	}
+}
+
+func TestParseUploadChecksum(t *testing.T) {
+	t.Parallel()
+	sum := sha1.Sum([]byte("hello"))
+	header := http.Header{}
+	header.Set("Upload-Checksum", "sha1 "+base64.StdEncoding.EncodeToString(sum[:]))
+	checksum, err := parseUploadChecksum(header)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	checksum.hash.Write([]byte("hello"))
+	if string(checksum.hash.Sum(nil)) != string(checksum.expected) {
+		t.Fatalf("Expected checksum of hello to match")
+	}
+
+	header.Set("Upload-Checksum", "crc32 AAAA")
+	if _, err := parseUploadChecksum(header); err == nil {
+		t.Fatalf("Expected error for unsupported algorithm, got none")
+	}
+}
+
+func TestTusOptions(t *testing.T) {
+	t.Parallel()
+	w := httptest.NewRecorder()
+	newTestAPI().TusOptions(w, httptest.NewRequest(http.MethodOptions, "/tus/", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", w.Code)
+	}
+	if w.Header().Get("Tus-Version") != TusVersion {
+		t.Fatalf("Expected Tus-Version %s, got %q", TusVersion, w.Header().Get("Tus-Version"))
+	}
+	if w.Header().Get("Tus-Extension") != tusExtensions {
+		t.Fatalf("Expected Tus-Extension %s, got %q", tusExtensions, w.Header().Get("Tus-Extension"))
+	}
+}
+
+func TestTusCreate_BadRequest(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		headers map[string]string
+		status  int
+	}{
+		{map[string]string{"Upload-Length": "10"}, http.StatusPreconditionFailed},
+		{map[string]string{"Tus-Resumable": TusVersion}, http.StatusBadRequest},
+		{map[string]string{"Tus-Resumable": TusVersion, "Upload-Length": "-1"}, http.StatusBadRequest},
+		{map[string]string{"Tus-Resumable": TusVersion, "Upload-Defer-Length": "1"}, http.StatusBadRequest},
+		{map[string]string{"Tus-Resumable": TusVersion, "Upload-Length": "10", "Upload-Metadata": "a !"}, http.StatusBadRequest},
+		{map[string]string{"Tus-Resumable": TusVersion, "Upload-Length": "1099511627777"}, http.StatusRequestEntityTooLarge},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/tus/", nil)
+		for key, value := range c.headers {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		newTestAPI().TusCreate(w, req)
+		if w.Code != c.status {
+			t.Fatalf("Expected status %d for %v, got %d", c.status, c.headers, w.Code)
+		}
+		if w.Header().Get("Tus-Resumable") != TusVersion {
+			t.Fatalf("Expected Tus-Resumable header on every response")
+		}
+	}
+}
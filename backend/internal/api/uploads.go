@@ -0,0 +1,122 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+	"github.com/google/uuid"
+)
+
+// MaxPartsCount is the largest number of parts a single upload can be split into.
+const MaxPartsCount = 10000
+
+type createUploadRequest struct {
+	Size       int    `json:"size"`
+	MimeType   string `json:"mime_type"`
+	PartsCount int    `json:"parts_count,omitempty"`
+	PartSize   int    `json:"part_size,omitempty"`
+}
+
+type partResponse struct {
+	PartNumber int    `json:"part_number"`
+	ObjectKey  string `json:"object_key"`
+	ByteOffset int64  `json:"byte_offset"`
+	ByteSize   int64  `json:"byte_size"`
+}
+
+type createUploadResponse struct {
+	ID         uuid.UUID      `json:"id"`
+	Size       int            `json:"size"`
+	MimeType   string         `json:"mime_type"`
+	PartsCount int            `json:"parts_count"`
+	Parts      []partResponse `json:"parts"`
+}
+
+// validate checks the request and returns the number of parts to create.
+func (req createUploadRequest) validate() (int, error) {
+	if req.Size <= 0 {
+		return 0, errors.New("size must be positive")
+	}
+	if req.MimeType == "" {
+		return 0, errors.New("mime_type is required")
+	}
+	if _, _, err := mime.ParseMediaType(req.MimeType); err != nil {
+		return 0, fmt.Errorf("mime_type is invalid: %w", err)
+	}
+
+	partsCount := req.PartsCount
+	switch {
+	case req.PartsCount != 0 && req.PartSize != 0:
+		return 0, errors.New("only one of parts_count and part_size can be provided")
+	case req.PartSize < 0:
+		return 0, errors.New("part_size must be positive")
+	case req.PartSize > 0:
+		partsCount = (req.Size + req.PartSize - 1) / req.PartSize
+	case req.PartsCount <= 0:
+		return 0, errors.New("one of parts_count or part_size must be positive")
+	}
+	if partsCount > MaxPartsCount {
+		return 0, fmt.Errorf("an upload cannot have more than %d parts", MaxPartsCount)
+	}
+	if partsCount > req.Size {
+		return 0, errors.New("an upload cannot have more parts than bytes")
+	}
+	return partsCount, nil
+}
+
+// CreateUpload handles POST /uploads. It creates an upload with a server
+// generated ID and responds with the object key and byte range of every part.
+func (a *API) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	var req createUploadRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	partsCount, err := req.validate()
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	owner := requestOwner(r)
+	id := uuid.New()
+	err = db.CreateUpload(ctx, owner, id, partsCount, req.Size, req.MimeType)
+	var quotaExceeded db.ErrQuotaExceeded
+	if errors.As(err, &quotaExceeded) {
+		writeError(w, quotaExceededStatus(quotaExceeded), err.Error())
+		return
+	} else if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	parts, err := db.GetUploadParts(ctx, owner, id)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	upload := db.Upload{ID: id, PartsCount: partsCount, Size: req.Size, MimeType: req.MimeType}
+	resp := createUploadResponse{
+		ID:         id,
+		Size:       req.Size,
+		MimeType:   req.MimeType,
+		PartsCount: partsCount,
+		Parts:      make([]partResponse, len(parts)),
+	}
+	for i, part := range parts {
+		offset, size := upload.PartRange(part.PartNumber)
+		resp.Parts[i] = partResponse{
+			PartNumber: part.PartNumber,
+			ObjectKey:  part.ObjectKey,
+			ByteOffset: offset,
+			ByteSize:   size,
+		}
+	}
+	w.Header().Set("Location", "/uploads/"+id.String())
+	writeJSON(w, http.StatusCreated, resp)
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCreateUploadRequestValidate(t *testing.T) {
+	t.Parallel()
+	partsCount, err := createUploadRequest{Size: 100, MimeType: "image/png", PartSize: 30}.validate()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if partsCount != 4 {
+		t.Fatalf("Expected 4 parts, got %d", partsCount)
+	}
+
+	invalid := map[string]createUploadRequest{
+		"zero size":            {Size: 0, MimeType: "image/png", PartsCount: 1},
+		"missing mime type":    {Size: 10, PartsCount: 1},
+		"invalid mime type":    {Size: 10, MimeType: "image/png; =", PartsCount: 1},
+		"no parts":             {Size: 10, MimeType: "image/png"},
+		"both parts and size":  {Size: 10, MimeType: "image/png", PartsCount: 1, PartSize: 10},
+		"more parts than size": {Size: 10, MimeType: "image/png", PartsCount: 11},
+		"too many parts":       {Size: MaxPartsCount + 1, MimeType: "image/png", PartSize: 1},
+	}
+	for name, req := range invalid {
+		if _, err := req.validate(); err == nil {
+			t.Fatalf("Expected error for %s, got none", name)
+		}
+	}
+}
+
+func TestCreateUpload_BadRequest(t *testing.T) {
+	t.Parallel()
+	cases := map[string]int{
+		`not json`: http.StatusBadRequest,
+		`{"size": 10, "mime_type": "image/png", "parts_count": 1, "unknown": 1}`: http.StatusBadRequest,
+		`{"size": 10, "mime_type": "image/png"}`:                                 http.StatusUnprocessableEntity,
+	}
+	for body, status := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/uploads", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		newTestAPI().CreateUpload(w, req)
+		if w.Code != status {
+			t.Fatalf("Expected status %d for %s, got %d", status, body, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), `"error"`) {
+			t.Fatalf("Expected JSON error body, got %s", w.Body.String())
+		}
+	}
+}
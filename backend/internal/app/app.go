@@ -0,0 +1,180 @@
+// Package app wires the dependencies of the backend together and serves its
+// routes.
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/api"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/auth"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/config"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/gc"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/health"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/metrics"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/middleware"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/ratelimit"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/server"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/storage"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// App owns the configuration, database pool, object store and logger of the
+// backend, and the handlers built on them.
+type App struct {
+	config         config.Config
+	pool           *pgxpool.Pool
+	bucket         *storage.Bucket
+	logger         *slog.Logger
+	api            *api.API
+	server         *server.Server
+	collector      *gc.Collector
+	rateLimitStore ratelimit.Store
+	handler        http.Handler
+}
+
+// New creates an app on pool and store. The caller keeps ownership of both
+// and closes them once Run returns. In tests the pool may be nil, in which
+// case routes that use the database fail and /ready does not check it.
+func New(config config.Config, pool *pgxpool.Pool, store storage.ObjectStore, logger *slog.Logger) *App {
+	a := &App{
+		config: config,
+		pool:   pool,
+		bucket: storage.NewBucket(store),
+		logger: logger,
+	}
+	a.api = api.New(a.bucket, config.API)
+	a.collector = gc.New(pool, a.bucket, config.GC)
+	a.rateLimitStore = ratelimit.NewMemoryStore()
+	if config.RateLimit.Store == ratelimit.StoreCockroach && pool != nil {
+		a.rateLimitStore = ratelimit.NewDBStore(pool)
+	}
+	a.server = server.New(config.Server, health.NewChecker(config.Server.ReadyTimeout, config.Server.ReadyCacheTTL, a.checks()...))
+	a.handler = a.routes()
+	return a
+}
+
+// checks are the dependencies /ready reports on.
+func (a *App) checks() []health.Check {
+	checks := []health.Check{{Name: "storage", Check: a.bucket.Ping}}
+	if a.pool == nil {
+		return checks
+	}
+	return append(checks,
+		health.Check{Name: "database", Check: a.pool.Ping},
+		health.Check{Name: "schema", Check: func(ctx context.Context) error {
+			deployed, err := db.SchemaChangeDeployed(db.WithConnPool(ctx, a.pool), db.SchemaVersion)
+			if err != nil {
+				return err
+			}
+			if !deployed {
+				return fmt.Errorf("schema change %s is not deployed", db.SchemaVersion)
+			}
+			return nil
+		}},
+	)
+}
+
+// Handler returns the handler serving every route.
+func (a *App) Handler() http.Handler {
+	return a.handler
+}
+
+func (a *App) routes() http.Handler {
+	limiter := middleware.NewRateLimiter(a.rateLimitStore, a.config.RateLimit)
+	read := func(handler http.HandlerFunc) http.Handler {
+		return middleware.RequireScope(auth.ScopeUploadRead, handler)
+	}
+	write := func(handler http.HandlerFunc) http.Handler {
+		return middleware.RequireScope(auth.ScopeUploadWrite, handler)
+	}
+	remove := func(handler http.HandlerFunc) http.Handler {
+		return middleware.RequireScope(auth.ScopeUploadDelete, handler)
+	}
+	// Routes that stream file content, or compose it like completing an
+	// upload, get long timeouts and count against the bandwidth limit
+	transfer := func(handler http.HandlerFunc) http.HandlerFunc {
+		return middleware.ExtendDeadlines(a.config.Server.TransferTimeout, limiter.Bandwidth(handler))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", a.server.Health)
+	mux.HandleFunc("GET /ready", a.server.Ready)
+	// Scraped by Prometheus, which should reach it only from inside the network
+	mux.Handle("GET /metrics", metrics.Handler())
+
+	// Only the API counts against the request limit, so that probes and
+	// scrapes are never throttled
+	handle := func(pattern string, handler http.Handler) {
+		mux.Handle(pattern, limiter.Requests(handler))
+	}
+	handle("POST /uploads", write(limiter.Creates(a.api.CreateUpload)))
+	handle("GET /uploads/{id}", read(a.api.GetUploadStatus))
+	handle("PUT /uploads/{id}/parts/{n}", write(transfer(a.api.UploadPart)))
+	handle("POST /uploads/{id}/parts/{n}/upload-url", write(a.api.CreatePartUploadURL))
+	handle("POST /uploads/{id}/parts/{n}/confirm", write(a.api.ConfirmPartUpload))
+	handle("POST /uploads/{id}/complete", write(transfer(a.api.CompleteUpload)))
+	handle("GET /uploads/{id}/content", read(transfer(a.api.DownloadUpload)))
+	handle("POST /uploads/{id}/shares", write(a.api.CreateShare))
+	handle("GET /quota", read(a.api.GetQuota))
+	// Share links and signed URLs carry their own authorization
+	handle("GET /s/{token}", transfer(a.api.DownloadShare))
+	handle("PUT /storage/{key}", transfer(a.api.PutSignedObject))
+	handle("OPTIONS /tus/", http.HandlerFunc(a.api.TusOptions))
+	handle("POST /tus/{$}", write(limiter.Creates(a.api.TusCreate)))
+	handle("HEAD /tus/{id}", write(a.api.TusHead))
+	handle("PATCH /tus/{id}", write(transfer(a.api.TusPatch)))
+	handle("DELETE /tus/{id}", remove(a.api.TusDelete))
+
+	return middleware.Compose(
+		middleware.Authenticate(auth.NewAuthenticator(a.config.Auth)),
+		middleware.ConnPool(a.pool),
+		middleware.CORS(a.config.CORS),
+		middleware.Recover,
+		middleware.Metrics(mux),
+		middleware.Logger(a.logger),
+		middleware.Tracing(mux),
+	)(mux)
+}
+
+// Run serves the app until ctx is done and the server has drained. The
+// garbage collector and the sweep of rate limit buckets run in the
+// background meanwhile, and are stopped only once the server has drained.
+func (a *App) Run(ctx context.Context) error {
+	background, stopBackground := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	if a.pool != nil {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			a.collector.Run(background)
+		}()
+	}
+	if dbStore, ok := a.rateLimitStore.(*ratelimit.DBStore); ok {
+		// Buckets idle for a day have refilled under any sensible limit
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			dbStore.Sweep(background, time.Hour, 24*time.Hour)
+		}()
+	}
+
+	err := a.server.Run(ctx, a.handler)
+
+	cleanup, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if a.pool != nil {
+		cleanup = db.WithConnPool(cleanup, a.pool)
+	}
+	if failed := a.api.FailInterruptedParts(cleanup); failed > 0 {
+		a.logger.Info("Marked interrupted parts as failed", "parts", failed)
+	}
+	stopBackground()
+	workers.Wait()
+	return err
+}
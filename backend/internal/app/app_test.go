@@ -0,0 +1,97 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/config"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/ratelimit"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/storage"
+	"github.com/google/uuid"
+)
+
+// newTestApp creates an app without a database on an in-memory store.
+func newTestApp() (*App, *storage.HMACSigner) {
+	store := storage.NewHMACSigner(storage.NewMemoryStore(), []byte("key"), "/storage")
+	logger := slog.New(slog.NewTextHandler(&strings.Builder{}, nil))
+	return New(config.Default(), nil, store, logger), store
+}
+
+func TestHealth(t *testing.T) {
+	t.Parallel()
+	app, _ := newTestApp()
+	w := httptest.NewRecorder()
+	app.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if w.Header().Get("X-Request-ID") == "" {
+		t.Fatalf("Expected the request to go through the middleware")
+	}
+}
+
+func TestHealth_NotRateLimited(t *testing.T) {
+	t.Parallel()
+	store := storage.NewMemoryStore()
+	logger := slog.New(slog.NewTextHandler(&strings.Builder{}, nil))
+	config := config.Default()
+	config.RateLimit.Requests = ratelimit.Every(1, time.Minute)
+	handler := New(config, nil, store, logger).Handler()
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/quota", nil))
+	if w.Header().Get("RateLimit-Limit") != "1" {
+		t.Fatalf("Expected API routes to be rate limited, got RateLimit-Limit %q", w.Header().Get("RateLimit-Limit"))
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	t.Parallel()
+	app, _ := newTestApp()
+	w := httptest.NewRecorder()
+	app.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/uploads/"+uuid.NewString(), nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401 without credentials, got %d", w.Code)
+	}
+}
+
+func TestSignedUpload(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	app, store := newTestApp()
+	signed, err := store.SignedUploadURL(ctx, "upload-1-0", storage.SignedUploadOptions{
+		ContentType:   "application/octet-stream",
+		ContentLength: 5,
+		Expires:       time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Failed to sign URL: %v", err)
+	}
+
+	r := httptest.NewRequest(http.MethodPut, signed.URL, strings.NewReader("hello"))
+	r.Header.Set("Content-Type", "application/octet-stream")
+	w := httptest.NewRecorder()
+	app.Handler().ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	data, err := store.Download(ctx, "upload-1-0")
+	if err != nil {
+		t.Fatalf("Failed to download object: %v", err)
+	}
+	if string(data) != "hello" {
+		t.Fatalf("Expected hello, got %s", string(data))
+	}
+}
@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrNoCredentials      = errors.New("no credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Config holds the keys JWTs are verified with. Several keys of each kind may
+// be configured so that keys can be rotated.
+type Config struct {
+	HS256Secrets      [][]byte
+	Ed25519PublicKeys []ed25519.PublicKey
+	// Issuer and Audience, if set, must match the iss and aud claims.
+	Issuer   string
+	Audience string
+}
+
+// ConfigFromEnv reads AUTH_JWT_HS256_SECRETS, a comma separated list of
+// secrets, AUTH_JWT_ED25519_PUBLIC_KEY_FILES, a comma separated list of PEM
+// files, AUTH_JWT_ISSUER and AUTH_JWT_AUDIENCE.
+func ConfigFromEnv(getenv func(string) string) (Config, error) {
+	config := Config{
+		Issuer:   getenv("AUTH_JWT_ISSUER"),
+		Audience: getenv("AUTH_JWT_AUDIENCE"),
+	}
+	for _, secret := range splitList(getenv("AUTH_JWT_HS256_SECRETS")) {
+		config.HS256Secrets = append(config.HS256Secrets, []byte(secret))
+	}
+	for _, file := range splitList(getenv("AUTH_JWT_ED25519_PUBLIC_KEY_FILES")) {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return config, fmt.Errorf("reading Ed25519 public key: %w", err)
+		}
+		key, err := jwt.ParseEdPublicKeyFromPEM(data)
+		if err != nil {
+			return config, fmt.Errorf("parsing Ed25519 public key %s: %w", file, err)
+		}
+		config.Ed25519PublicKeys = append(config.Ed25519PublicKeys, key.(ed25519.PublicKey))
+	}
+	return config, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	// Scope is a space separated list of scopes, as in RFC 8693.
+	Scope string `json:"scope"`
+}
+
+// Authenticator identifies the caller of a request from an API key, given in
+// an X-API-Key header or as a bearer token, or from a JWT bearer token.
+type Authenticator struct {
+	config Config
+	parser *jwt.Parser
+	// LookupAPIKey finds an API key by its hash.
+	LookupAPIKey func(ctx context.Context, keyHash []byte) (*db.APIKey, error)
+	// LookupUser finds or creates the user for a subject.
+	LookupUser func(ctx context.Context, subject string) (*db.User, error)
+	now        func() time.Time
+}
+
+func NewAuthenticator(config Config) *Authenticator {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30 * time.Second),
+	}
+	if config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(config.Issuer))
+	}
+	if config.Audience != "" {
+		options = append(options, jwt.WithAudience(config.Audience))
+	}
+	return &Authenticator{
+		config:       config,
+		parser:       jwt.NewParser(options...),
+		LookupAPIKey: db.GetAPIKeyByHash,
+		LookupUser:   db.GetOrCreateUser,
+		now:          time.Now,
+	}
+}
+
+// Authenticate returns the caller of r. It fails with ErrNoCredentials if
+// the request carries none, and ErrInvalidCredentials if they are not valid.
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	principal, err := a.authenticate(r)
+	if err != nil {
+		return nil, err
+	}
+	user, err := a.LookupUser(r.Context(), principal.Subject)
+	if err != nil {
+		return nil, err
+	}
+	principal.UserID = user.ID
+	return principal, nil
+}
+
+func (a *Authenticator) authenticate(r *http.Request) (*Principal, error) {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return a.authenticateAPIKey(r.Context(), key)
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, ErrNoCredentials
+	}
+	token = strings.TrimSpace(token)
+	if isAPIKey(token) {
+		return a.authenticateAPIKey(r.Context(), token)
+	}
+	return a.authenticateJWT(token)
+}
+
+func (a *Authenticator) authenticateAPIKey(ctx context.Context, key string) (*Principal, error) {
+	apiKey, err := a.LookupAPIKey(ctx, HashAPIKey(key))
+	var notFound db.ErrAPIKeyNotFound
+	if errors.As(err, &notFound) {
+		return nil, ErrInvalidCredentials
+	} else if err != nil {
+		return nil, err
+	}
+	if !apiKey.Valid(a.now()) {
+		return nil, ErrInvalidCredentials
+	}
+	return &Principal{Subject: apiKey.Subject, Scopes: apiKey.Scopes, Method: MethodAPIKey, KeyID: apiKey.ID}, nil
+}
+
+func (a *Authenticator) authenticateJWT(token string) (*Principal, error) {
+	var claims tokenClaims
+	_, err := a.parser.ParseWithClaims(token, &claims, a.verificationKeys)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidCredentials)
+	}
+	return &Principal{Subject: claims.Subject, Scopes: strings.Fields(claims.Scope), Method: MethodJWT}, nil
+}
+
+// verificationKeys returns the configured keys for the token's algorithm.
+func (a *Authenticator) verificationKeys(token *jwt.Token) (any, error) {
+	var keys []jwt.VerificationKey
+	switch token.Method {
+	case jwt.SigningMethodHS256:
+		for _, secret := range a.config.HS256Secrets {
+			keys = append(keys, secret)
+		}
+	case jwt.SigningMethodEdDSA:
+		for _, key := range a.config.Ed25519PublicKeys {
+			keys = append(keys, crypto.PublicKey(key))
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys configured for %s", token.Method.Alg())
+	}
+	return jwt.VerificationKeySet{Keys: keys}, nil
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func signToken(t *testing.T, method jwt.SigningMethod, key any, claims jwt.Claims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return token
+}
+
+func testUser(ctx context.Context, subject string) (*db.User, error) {
+	return &db.User{ID: uuid.NewSHA1(uuid.NameSpaceURL, []byte(subject)), Subject: subject}, nil
+}
+
+func bearerRequest(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/uploads", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+func TestAuthenticate_JWT(t *testing.T) {
+	t.Parallel()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	authenticator := NewAuthenticator(Config{
+		HS256Secrets:      [][]byte{[]byte("old secret"), []byte("secret")},
+		Ed25519PublicKeys: []ed25519.PublicKey{publicKey},
+		Issuer:            "transfer",
+	})
+	authenticator.LookupUser = testUser
+	valid := tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "user-1",
+			Issuer:    "transfer",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Scope: "upload:read upload:write",
+	}
+
+	for _, token := range []string{
+		signToken(t, jwt.SigningMethodHS256, []byte("secret"), valid),
+		signToken(t, jwt.SigningMethodHS256, []byte("old secret"), valid),
+		signToken(t, jwt.SigningMethodEdDSA, privateKey, valid),
+	} {
+		principal, err := authenticator.Authenticate(bearerRequest(token))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if principal.Subject != "user-1" || principal.Method != MethodJWT || principal.UserID == uuid.Nil {
+			t.Fatalf("Expected JWT principal user-1, got %+v", principal)
+		}
+		if !slices.Equal(principal.Scopes, []string{ScopeUploadRead, ScopeUploadWrite}) {
+			t.Fatalf("Expected scopes upload:read and upload:write, got %v", principal.Scopes)
+		}
+	}
+
+	expired := valid
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	wrongIssuer := valid
+	wrongIssuer.Issuer = "someone else"
+	noExpiry := valid
+	noExpiry.ExpiresAt = nil
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	for name, token := range map[string]string{
+		"wrong secret": signToken(t, jwt.SigningMethodHS256, []byte("wrong"), valid),
+		"wrong key":    signToken(t, jwt.SigningMethodEdDSA, otherKey, valid),
+		"expired":      signToken(t, jwt.SigningMethodHS256, []byte("secret"), expired),
+		"wrong issuer": signToken(t, jwt.SigningMethodHS256, []byte("secret"), wrongIssuer),
+		"no expiry":    signToken(t, jwt.SigningMethodHS256, []byte("secret"), noExpiry),
+		"unsigned":     signToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid),
+		"malformed":    "not.a.token",
+	} {
+		if _, err := authenticator.Authenticate(bearerRequest(token)); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("Expected ErrInvalidCredentials for %s, got %v", name, err)
+		}
+	}
+}
+
+func TestAuthenticate_APIKey(t *testing.T) {
+	t.Parallel()
+	key, keyHash := NewAPIKey()
+	revokedKey, revokedHash := NewAPIKey()
+	revokedAt := time.Now()
+	authenticator := NewAuthenticator(Config{})
+	authenticator.LookupUser = testUser
+	authenticator.LookupAPIKey = func(ctx context.Context, hash []byte) (*db.APIKey, error) {
+		switch string(hash) {
+		case string(keyHash):
+			return &db.APIKey{Subject: "service", Scopes: []string{ScopeUploadRead}}, nil
+		case string(revokedHash):
+			return &db.APIKey{Subject: "service", RevokedAt: &revokedAt}, nil
+		}
+		return nil, db.ErrAPIKeyNotFound{}
+	}
+
+	principal, err := authenticator.Authenticate(bearerRequest(key))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if principal.Subject != "service" || principal.Method != MethodAPIKey || !principal.HasScope(ScopeUploadRead) {
+		t.Fatalf("Expected API key principal with upload:read, got %+v", principal)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/uploads", nil)
+	r.Header.Set("X-API-Key", key)
+	if _, err := authenticator.Authenticate(r); err != nil {
+		t.Fatalf("Expected X-API-Key to authenticate, got %v", err)
+	}
+
+	if _, err := authenticator.Authenticate(bearerRequest(revokedKey)); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Expected ErrInvalidCredentials for a revoked key, got %v", err)
+	}
+	if _, err := authenticator.Authenticate(bearerRequest(APIKeyPrefix + "unknown")); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Expected ErrInvalidCredentials for an unknown key, got %v", err)
+	}
+	if _, err := authenticator.Authenticate(httptest.NewRequest(http.MethodGet, "/uploads", nil)); !errors.Is(err, ErrNoCredentials) {
+		t.Fatalf("Expected ErrNoCredentials, got %v", err)
+	}
+}
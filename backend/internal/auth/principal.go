@@ -0,0 +1,51 @@
+// Package auth authenticates callers of the API with API keys and JWT bearer
+// tokens.
+package auth
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ScopeUploadRead   = "upload:read"
+	ScopeUploadWrite  = "upload:write"
+	ScopeUploadDelete = "upload:delete"
+)
+
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// Principal is an authenticated caller.
+type Principal struct {
+	Subject string
+	// UserID is the user the subject is recorded as, who owns its uploads.
+	UserID uuid.UUID
+	Scopes []string
+	// Method is how the caller authenticated, MethodAPIKey or MethodJWT.
+	Method string
+	// KeyID is the API key used, if Method is MethodAPIKey.
+	KeyID uuid.UUID
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+type ctxKey string
+
+const principalKey ctxKey = "principal"
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey, principal)
+}
+
+// PrincipalFrom returns the caller authenticated for the request, if any.
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey).(*Principal)
+	return principal, ok
+}
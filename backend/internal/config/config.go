@@ -0,0 +1,222 @@
+// Package config loads the configuration of the backend from environment
+// variables and an optional YAML or TOML file.
+//
+// The file holds the same settings as the environment variables, in
+// lowercase, and nested keys are joined with underscores, so
+//
+//	rate_limit:
+//	  requests: 600/1m
+//	cors:
+//	  allowed_origins: [https://transfer.example.com]
+//
+// sets RATE_LIMIT_REQUESTS and CORS_ALLOWED_ORIGINS. Lists are joined with
+// commas. If ENV is set, settings in the overlay next to the file named after
+// it, such as config.production.yaml for config.yaml, replace those in the
+// file. Environment variables that are set take precedence over both.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/api"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/auth"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/gc"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/middleware"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/ratelimit"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/server"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/storage"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/tracing"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	// Env is the environment the backend runs in, such as development.
+	Env       string
+	Server    server.Config
+	Database  db.Config
+	Storage   storage.Config
+	API       api.Config
+	GC        gc.Config
+	Auth      auth.Config
+	RateLimit ratelimit.Config
+	CORS      middleware.CORSConfig
+	Tracing   tracing.Config
+}
+
+// Default returns the default of every setting. Settings without a default,
+// such as the database connection string, are left empty.
+func Default() Config {
+	return Config{
+		Server:    server.DefaultConfig(),
+		Storage:   storage.DefaultConfig(),
+		API:       api.DefaultConfig(),
+		GC:        gc.DefaultConfig(),
+		RateLimit: ratelimit.DefaultConfig(),
+		CORS:      middleware.DefaultCORSConfig(),
+		Tracing:   tracing.DefaultConfig(),
+	}
+}
+
+// Load reads ENV and CONFIG_FILE, the path of the config file, with getenv,
+// then reads every setting. All invalid, missing and unknown settings are
+// reported together.
+func Load(getenv func(string) string) (Config, error) {
+	config := Config{Env: getenv("ENV")}
+	s := &source{getenv: getenv, used: map[string]bool{}}
+	if path := getenv("CONFIG_FILE"); path != "" {
+		settings, err := readFile(path)
+		if err != nil {
+			return config, err
+		}
+		s.files = append(s.files, settings)
+		if config.Env != "" {
+			settings, err := readFile(overlayPath(path, config.Env))
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return config, err
+			}
+			if err == nil {
+				s.files = append(s.files, settings)
+			}
+		}
+	}
+
+	var errs []error
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	var err error
+	config.Server, err = server.ConfigFromEnv(s.get)
+	check(err)
+	config.Database, err = db.ConfigFromEnv(s.get)
+	check(err)
+	config.Storage, err = storage.ConfigFromEnv(s.get)
+	check(err)
+	config.API, err = api.ConfigFromEnv(s.get)
+	check(err)
+	config.GC, err = gc.ConfigFromEnv(s.get)
+	check(err)
+	config.Auth, err = auth.ConfigFromEnv(s.get)
+	check(err)
+	config.RateLimit, err = ratelimit.ConfigFromEnv(s.get)
+	check(err)
+	config.CORS, err = middleware.CORSConfigFromEnv(s.get)
+	check(err)
+	config.Tracing, err = tracing.ConfigFromEnv(s.get)
+	check(err)
+	for _, file := range s.files {
+		for _, name := range file.names() {
+			if !s.used[name] {
+				errs = append(errs, fmt.Errorf("unknown setting %s in %s", strings.ToLower(name), file.path))
+			}
+		}
+	}
+	return config, errors.Join(errs...)
+}
+
+// source looks settings up in the environment, then in config files from the
+// last to the first, and records which settings were looked up.
+type source struct {
+	getenv func(string) string
+	files  []settings
+	used   map[string]bool
+}
+
+func (s *source) get(name string) string {
+	s.used[name] = true
+	if value := s.getenv(name); value != "" {
+		return value
+	}
+	for i := len(s.files) - 1; i >= 0; i-- {
+		if value, ok := s.files[i].values[name]; ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// settings are the values in a config file by variable name.
+type settings struct {
+	path   string
+	values map[string]string
+}
+
+func (s settings) names() []string {
+	names := make([]string, 0, len(s.values))
+	for name := range s.values {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// overlayPath returns the path of the overlay of path for env.
+func overlayPath(path string, env string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + env + ext
+}
+
+func readFile(path string) (settings, error) {
+	file := settings{path: path, values: map[string]string{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return file, fmt.Errorf("reading config file: %w", err)
+	}
+	var tree map[string]any
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return file, fmt.Errorf("unknown config file format: %q", ext)
+	}
+	if err != nil {
+		return file, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	if err := flatten("", tree, file.values); err != nil {
+		return file, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return file, nil
+}
+
+// flatten adds the values in tree to values, named by their keys in
+// uppercase joined with underscores.
+func flatten(prefix string, tree map[string]any, values map[string]string) error {
+	for key, value := range tree {
+		name := strings.ToUpper(key)
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+		switch value := value.(type) {
+		case map[string]any:
+			if err := flatten(name, value, values); err != nil {
+				return err
+			}
+		case []any:
+			items := make([]string, len(value))
+			for i, item := range value {
+				switch item.(type) {
+				case map[string]any, []any:
+					return fmt.Errorf("%s must be a list of values", strings.ToLower(name))
+				}
+				items[i] = fmt.Sprint(item)
+			}
+			values[name] = strings.Join(items, ",")
+		case nil:
+			values[name] = ""
+		default:
+			values[name] = fmt.Sprint(value)
+		}
+	}
+	return nil
+}
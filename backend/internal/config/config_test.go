@@ -0,0 +1,136 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/ratelimit"
+)
+
+func lookup(env map[string]string) func(string) string {
+	return func(name string) string {
+		return env[name]
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	writeFile(t, path, `
+cockroach_connection_string: postgresql://root@localhost:26257/transfer
+storage:
+  backend: memory
+server:
+  transfer_timeout: 2h
+  shutdown_delay: 1s
+cors:
+  allowed_origins:
+    - https://a.example.com
+    - https://b.example.com
+rate_limit:
+  requests: 10/1s
+`)
+	writeFile(t, filepath.Join(dir, "config.production.yaml"), `
+server:
+  shutdown_delay: 10s
+rate_limit:
+  requests:
+`)
+
+	config, err := Load(lookup(map[string]string{
+		"ENV":          "production",
+		"CONFIG_FILE":  path,
+		"BACKEND_PORT": "9090",
+	}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if config.Env != "production" {
+		t.Fatalf("Expected env production, got %q", config.Env)
+	}
+	if config.Server.Port != "9090" {
+		t.Fatalf("Expected port from the environment, got %q", config.Server.Port)
+	}
+	if config.Server.TransferTimeout != 2*time.Hour {
+		t.Fatalf("Expected transfer timeout from the file, got %s", config.Server.TransferTimeout)
+	}
+	if config.Server.ShutdownDelay != 10*time.Second {
+		t.Fatalf("Expected shutdown delay from the overlay, got %s", config.Server.ShutdownDelay)
+	}
+	if config.RateLimit.Requests != ratelimit.DefaultConfig().Requests {
+		t.Fatalf("Expected an empty overlay value to restore the default, got %+v", config.RateLimit.Requests)
+	}
+	if got := strings.Join(config.CORS.AllowedOrigins, " "); got != "https://a.example.com https://b.example.com" {
+		t.Fatalf("Expected allowed origins from the file, got %q", got)
+	}
+	if config.Storage.Backend != "memory" {
+		t.Fatalf("Expected memory storage, got %q", config.Storage.Backend)
+	}
+}
+
+func TestLoad_TOML(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	writeFile(t, path, `
+cockroach_connection_string = "postgresql://root@localhost:26257/transfer"
+
+[storage]
+backend = "memory"
+
+[gc]
+batch_size = 10
+`)
+
+	// The overlay for ENV is optional
+	config, err := Load(lookup(map[string]string{"ENV": "development", "CONFIG_FILE": path}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if config.GC.BatchSize != 10 {
+		t.Fatalf("Expected batch size 10, got %d", config.GC.BatchSize)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, `
+storage:
+  backend: memory
+  bucket: transfer
+gc:
+  interval: often
+`)
+
+	_, err := Load(lookup(map[string]string{"CONFIG_FILE": path}))
+	if err == nil {
+		t.Fatalf("Expected an error")
+	}
+	for _, want := range []string{
+		"COCKROACH_CONNECTION_STRING is not set",
+		`invalid GC_INTERVAL: "often"`,
+		"unknown setting storage_bucket in " + path,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("Expected error to contain %q, got %q", want, err)
+		}
+	}
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if _, err := Load(lookup(map[string]string{"CONFIG_FILE": path})); err == nil {
+		t.Fatalf("Expected an error for a missing config file")
+	}
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKey grants its scopes to a subject. Only a hash of the key is stored.
+type APIKey struct {
+	ID        uuid.UUID
+	Name      string
+	Subject   string
+	Scopes    []string
+	ExpiresAt *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// Valid reports whether the key can be used at now.
+func (k APIKey) Valid(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+type ErrAPIKeyNotFound struct{}
+
+func (e ErrAPIKeyNotFound) Error() string {
+	return "API key not found"
+}
+
+func CreateAPIKey(ctx context.Context, key APIKey, keyHash []byte) error {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return errors.New("connection not found in context")
+	}
+	_, err := conn.Exec(ctx, "CALL auth.create_api_key($1, $2, $3, $4, $5, $6)", key.ID, key.Name, key.Subject, keyHash, key.Scopes, key.ExpiresAt)
+	return err
+}
+
+func GetAPIKeyByHash(ctx context.Context, keyHash []byte) (*APIKey, error) {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return nil, errors.New("connection not found in context")
+	}
+	var key APIKey
+	row := conn.QueryRow(ctx, "SELECT id, name, subject, scopes, expires_at, revoked_at, created_at FROM auth.api_keys WHERE key_hash = $1", keyHash)
+	err := row.Scan(&key.ID, &key.Name, &key.Subject, &key.Scopes, &key.ExpiresAt, &key.RevokedAt, &key.CreatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			return nil, ErrAPIKeyNotFound{}
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func RevokeAPIKey(ctx context.Context, id uuid.UUID) error {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return errors.New("connection not found in context")
+	}
+	_, err := conn.Exec(ctx, "CALL auth.revoke_api_key($1)", id)
+	if err != nil {
+		if strings.Contains(err.Error(), "API key not found:") {
+			return fmt.Errorf("%w: %s", ErrAPIKeyNotFound{}, id)
+		}
+		return err
+	}
+	return nil
+}
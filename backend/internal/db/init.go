@@ -2,9 +2,11 @@ package db
 
 import (
 	"context"
-	"os"
+	"errors"
+	"fmt"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/multitracer"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -20,8 +22,30 @@ type ctxKey string
 const connPoolKey ctxKey = "pgx_conn_pool"
 const txKey ctxKey = "pgx_tx"
 
-func InitDBPool() (*pgxpool.Pool, func(), error) {
-	pool, err := pgxpool.New(context.Background(), os.Getenv("COCKROACH_CONNECTION_STRING"))
+type Config struct {
+	// ConnectionString is a PostgreSQL URL or keyword/value connection string.
+	ConnectionString string
+}
+
+// ConfigFromEnv reads COCKROACH_CONNECTION_STRING, which must be set.
+func ConfigFromEnv(getenv func(string) string) (Config, error) {
+	config := Config{ConnectionString: getenv("COCKROACH_CONNECTION_STRING")}
+	if config.ConnectionString == "" {
+		return config, errors.New("COCKROACH_CONNECTION_STRING is not set")
+	}
+	if _, err := pgxpool.ParseConfig(config.ConnectionString); err != nil {
+		return config, fmt.Errorf("invalid COCKROACH_CONNECTION_STRING: %w", err)
+	}
+	return config, nil
+}
+
+func InitDBPool(dbConfig Config) (*pgxpool.Pool, func(), error) {
+	config, err := pgxpool.ParseConfig(dbConfig.ConnectionString)
+	if err != nil {
+		return nil, nil, err
+	}
+	config.ConnConfig.Tracer = multitracer.New(queryTracer{}, queryLogger{})
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -2,13 +2,22 @@ package db
 
 import (
 	"context"
+	"os"
 	"testing"
 )
 
+func testConfig(t *testing.T) Config {
+	config, err := ConfigFromEnv(os.Getenv)
+	if err != nil {
+		t.Fatalf("Failed to read database config: %v", err)
+	}
+	return config
+}
+
 func TestInitDB(t *testing.T) {
 	t.Parallel()
 	// Call the InitDB function
-	dbpool, cleanup, err := InitDBPool()
+	dbpool, cleanup, err := InitDBPool(testConfig(t))
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
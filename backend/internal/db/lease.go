@@ -0,0 +1,42 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// AcquireLease takes or renews the named lease for holder until ttl from now,
+// by the database clock. It returns false if another holder has an unexpired
+// lease.
+func AcquireLease(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error) {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return false, errors.New("connection not found in context")
+	}
+	var currentHolder string
+	row := conn.QueryRow(ctx, `INSERT INTO upload.leases (name, holder, expires_at)
+		VALUES ($1, $2, now() + $3 * INTERVAL '1 millisecond')
+		ON CONFLICT (name) DO UPDATE SET holder = excluded.holder, expires_at = excluded.expires_at
+		WHERE upload.leases.holder = excluded.holder OR upload.leases.expires_at < now()
+		RETURNING holder`, name, holder, ttl.Milliseconds())
+	err := row.Scan(&currentHolder)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ReleaseLease gives up the named lease if holder has it.
+func ReleaseLease(ctx context.Context, name string, holder string) error {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return errors.New("connection not found in context")
+	}
+	_, err := conn.Exec(ctx, "DELETE FROM upload.leases WHERE name = $1 AND holder = $2", name, holder)
+	return err
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/logging"
+	"github.com/jackc/pgx/v5"
+)
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	sql  string
+	time time.Time
+}
+
+// queryLogger logs every query at debug level with the logger of the request
+// it was made for.
+type queryLogger struct{}
+
+func (queryLogger) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, queryStart{sql: data.SQL, time: time.Now()})
+}
+
+func (queryLogger) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	logger := logging.FromContext(ctx)
+	if !logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	start, _ := ctx.Value(queryStartKey{}).(queryStart)
+	attrs := []slog.Attr{
+		slog.String("sql", start.sql),
+		slog.Duration("duration", time.Since(start.time)),
+	}
+	if data.Err != nil {
+		attrs = append(attrs, slog.Any("error", data.Err))
+	} else {
+		attrs = append(attrs, slog.Int64("rows", data.CommandTag.RowsAffected()))
+	}
+	logger.LogAttrs(ctx, slog.LevelDebug, "Query", attrs...)
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Quota limits what a user may store. A nil limit is unlimited.
+type Quota struct {
+	MaxTotalBytes        *int64
+	MaxFileSize          *int64
+	MaxConcurrentUploads *int
+}
+
+// Usage is what counts against a user's quota. Concurrent uploads are those
+// still pending or in progress.
+type Usage struct {
+	TotalBytes        int64
+	UploadCount       int
+	ConcurrentUploads int
+}
+
+// The limits reported by ErrQuotaExceeded.
+const (
+	QuotaLimitTotalBytes        = "max_total_bytes"
+	QuotaLimitFileSize          = "max_file_size"
+	QuotaLimitConcurrentUploads = "max_concurrent_uploads"
+)
+
+type ErrQuotaExceeded struct {
+	UserID uuid.UUID
+	Limit  string
+}
+
+func (e ErrQuotaExceeded) Error() string {
+	return fmt.Sprintf("quota exceeded: %s", e.Limit)
+}
+
+// quotaExceeded reads which limit was hit from the exception raised by
+// upload.create_new_upload.
+func quotaExceeded(err error, userID uuid.UUID) ErrQuotaExceeded {
+	_, limit, _ := strings.Cut(err.Error(), "Quota exceeded: ")
+	limit, _, _ = strings.Cut(limit, " ")
+	return ErrQuotaExceeded{UserID: userID, Limit: limit}
+}
+
+// GetQuota returns the quota of a user, which is the default quota unless one
+// has been set for them.
+func GetQuota(ctx context.Context, userID uuid.UUID) (*Quota, error) {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return nil, errors.New("connection not found in context")
+	}
+	var quota Quota
+	row := conn.QueryRow(ctx, `SELECT q.max_total_bytes, q.max_file_size, q.max_concurrent_uploads FROM auth.quotas q WHERE q.user_id = $1
+		UNION ALL
+		SELECT d.max_total_bytes, d.max_file_size, d.max_concurrent_uploads FROM auth.default_quota d WHERE NOT EXISTS (SELECT 1 FROM auth.quotas WHERE user_id = $1)`, userID)
+	err := row.Scan(&quota.MaxTotalBytes, &quota.MaxFileSize, &quota.MaxConcurrentUploads)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			return &Quota{}, nil
+		}
+		return nil, err
+	}
+	return &quota, nil
+}
+
+// SetQuota sets the quota of a user, replacing the default quota.
+func SetQuota(ctx context.Context, userID uuid.UUID, quota Quota) error {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return errors.New("connection not found in context")
+	}
+	_, err := conn.Exec(ctx, "UPSERT INTO auth.quotas (user_id, max_total_bytes, max_file_size, max_concurrent_uploads, updated_at) VALUES ($1, $2, $3, $4, now())", userID, quota.MaxTotalBytes, quota.MaxFileSize, quota.MaxConcurrentUploads)
+	return err
+}
+
+func GetUsage(ctx context.Context, userID uuid.UUID) (*Usage, error) {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return nil, errors.New("connection not found in context")
+	}
+	var usage Usage
+	row := conn.QueryRow(ctx, "SELECT COALESCE(SUM(size), 0), COUNT(*), COUNT(*) FILTER (WHERE status IN ('pending', 'in_progress')) FROM upload.uploads WHERE owner_id = $1", userID)
+	err := row.Scan(&usage.TotalBytes, &usage.UploadCount, &usage.ConcurrentUploads)
+	if err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// TakeRateLimitTokens refills the token bucket under key at rate tokens per
+// second up to burst, then takes cost tokens from it if it holds enough, or
+// regardless if force is set. It returns whether the tokens were taken and
+// how many are left. The bucket is read and written in one statement, so
+// concurrent callers on any replica see each other's takes.
+func TakeRateLimitTokens(ctx context.Context, key string, rate float64, burst float64, cost float64, force bool) (bool, float64, error) {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return false, 0, errors.New("connection not found in context")
+	}
+	var allowed bool
+	var tokens float64
+	row := conn.QueryRow(ctx, `WITH bucket AS (
+			SELECT LEAST($2::FLOAT8, tokens + $3::FLOAT8 * EXTRACT(EPOCH FROM now() - updated_at)::FLOAT8) AS tokens
+			FROM auth.rate_limit_buckets WHERE key = $1 FOR UPDATE
+		), decision AS (
+			SELECT t.tokens, ($5::BOOL OR t.tokens >= $4::FLOAT8) AS allowed
+			FROM (SELECT COALESCE((SELECT tokens FROM bucket), $2::FLOAT8) AS tokens) t
+		), updated AS (
+			UPSERT INTO auth.rate_limit_buckets (key, tokens, updated_at)
+			SELECT $1, CASE WHEN allowed THEN tokens - $4::FLOAT8 ELSE tokens END, now() FROM decision
+			RETURNING tokens
+		)
+		SELECT d.allowed, u.tokens FROM decision d, updated u`, key, burst, rate, cost, force)
+	err := row.Scan(&allowed, &tokens)
+	if err != nil {
+		return false, 0, err
+	}
+	return allowed, tokens, nil
+}
+
+// DeleteIdleRateLimitBuckets deletes buckets untouched since before cutoff.
+func DeleteIdleRateLimitBuckets(ctx context.Context, cutoff time.Time) (int64, error) {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return 0, errors.New("connection not found in context")
+	}
+	tag, err := conn.Exec(ctx, "DELETE FROM auth.rate_limit_buckets WHERE updated_at < $1", cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
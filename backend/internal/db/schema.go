@@ -0,0 +1,28 @@
+package db
+
+import (
+	"context"
+	"errors"
+)
+
+// SchemaProject is the sqitch project the schema is deployed with.
+const SchemaProject = "db"
+
+// SchemaVersion is the last change in db/sqitch.plan, which the code expects
+// to be deployed. It must be updated with every new change.
+const SchemaVersion = "track_upload_activity"
+
+// SchemaChangeDeployed reports whether the sqitch change has been deployed,
+// according to the sqitch registry in the database.
+func SchemaChangeDeployed(ctx context.Context, change string) (bool, error) {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return false, errors.New("connection not found in context")
+	}
+	var deployed bool
+	row := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM sqitch.changes WHERE project = $1 AND change = $2)", SchemaProject, change)
+	if err := row.Scan(&deployed); err != nil {
+		return false, err
+	}
+	return deployed, nil
+}
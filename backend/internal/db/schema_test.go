@@ -0,0 +1,26 @@
+package db
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSchemaVersion(t *testing.T) {
+	t.Parallel()
+	plan, err := os.ReadFile("../../../db/sqitch.plan")
+	if err != nil {
+		t.Fatalf("Failed to read sqitch plan: %v", err)
+	}
+	var last string
+	for _, line := range strings.Split(string(plan), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "%") || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "@") {
+			continue
+		}
+		last = strings.Fields(line)[0]
+	}
+	if last != SchemaVersion {
+		t.Fatalf("Expected SchemaVersion to be the last change in the plan, %q, got %q", last, SchemaVersion)
+	}
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Share is a link for downloading a finalized upload, optionally protected by a
+// password and limited in time or number of downloads.
+type Share struct {
+	ID            uuid.UUID
+	UploadID      uuid.UUID
+	PasswordHash  *string
+	ExpiresAt     *time.Time
+	MaxDownloads  *int
+	DownloadCount int
+	CreatedAt     time.Time
+}
+
+// Available reports whether the share can still be downloaded at now.
+func (s Share) Available(now time.Time) bool {
+	if s.ExpiresAt != nil && !now.Before(*s.ExpiresAt) {
+		return false
+	}
+	return s.MaxDownloads == nil || s.DownloadCount < *s.MaxDownloads
+}
+
+type ErrShareNotFound struct{}
+
+func (e ErrShareNotFound) Error() string {
+	return "share not found"
+}
+
+type ErrShareUnavailable struct {
+	ShareID uuid.UUID
+}
+
+func (e ErrShareUnavailable) Error() string {
+	return fmt.Sprintf("share expired or download limit reached: %s", e.ShareID)
+}
+
+type ErrUploadNotFinalized struct {
+	UploadID uuid.UUID
+}
+
+func (e ErrUploadNotFinalized) Error() string {
+	return fmt.Sprintf("upload not finalized: %s", e.UploadID)
+}
+
+func CreateShare(ctx context.Context, share Share, tokenHash []byte) error {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return errors.New("connection not found in context")
+	}
+	_, err := conn.Exec(ctx, "CALL upload.create_share($1, $2, $3, $4, $5, $6)", share.ID, share.UploadID, tokenHash, share.PasswordHash, share.ExpiresAt, share.MaxDownloads)
+	if err != nil {
+		if strings.Contains(err.Error(), "Upload not found:") {
+			return ErrUploadNotFound{UploadID: share.UploadID}
+		}
+		if strings.Contains(err.Error(), "Upload not finalized:") {
+			return ErrUploadNotFinalized{UploadID: share.UploadID}
+		}
+		return err
+	}
+	return nil
+}
+
+func GetShareByTokenHash(ctx context.Context, tokenHash []byte) (*Share, error) {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return nil, errors.New("connection not found in context")
+	}
+	var share Share
+	row := conn.QueryRow(ctx, "SELECT id, upload_id, password_hash, expires_at, max_downloads, download_count, created_at FROM upload.shares WHERE token_hash = $1", tokenHash)
+	err := row.Scan(&share.ID, &share.UploadID, &share.PasswordHash, &share.ExpiresAt, &share.MaxDownloads, &share.DownloadCount, &share.CreatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			return nil, ErrShareNotFound{}
+		}
+		return nil, err
+	}
+	return &share, nil
+}
+
+// GetSharedUpload returns the upload a share links to, whoever owns it.
+func GetSharedUpload(ctx context.Context, shareID uuid.UUID) (*Upload, error) {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return nil, errors.New("connection not found in context")
+	}
+	upload, err := scanUpload(conn.QueryRow(ctx, selectUpload+" JOIN upload.shares s ON s.upload_id = u.id WHERE s.id = $1", shareID))
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			return nil, ErrShareNotFound{}
+		}
+		return nil, err
+	}
+	return upload, nil
+}
+
+// ClaimShareDownload counts a download of the share. The check and the
+// increment happen in one statement, so concurrent downloads can never exceed
+// the share's limit.
+func ClaimShareDownload(ctx context.Context, shareID uuid.UUID) error {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return errors.New("connection not found in context")
+	}
+	var downloadCount int
+	row := conn.QueryRow(ctx, "UPDATE upload.shares SET download_count = download_count + 1 WHERE id = $1 AND (expires_at IS NULL OR expires_at > now()) AND (max_downloads IS NULL OR download_count < max_downloads) RETURNING download_count", shareID)
+	err := row.Scan(&downloadCount)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			return ErrShareUnavailable{ShareID: shareID}
+		}
+		return err
+	}
+	return nil
+}
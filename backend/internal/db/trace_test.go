@@ -0,0 +1,19 @@
+package db
+
+import "testing"
+
+func TestQueryName(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"CALL upload.update_part($1, $2, $3)":         "CALL upload.update_part",
+		"call upload.delete_upload ($1)":              "CALL upload.delete_upload",
+		"SELECT id FROM upload.uploads WHERE id = $1": "SELECT",
+		"\n\t\tWITH bucket AS (SELECT 1) SELECT 1":    "WITH",
+		"": "query",
+	}
+	for sql, expected := range cases {
+		if name := queryName(sql); name != expected {
+			t.Fatalf("Expected %q for %q, got %q", expected, sql, name)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// TusUpload is a single part upload driven by the tus protocol, whose data
+// arrives as a series of segments.
+type TusUpload struct {
+	UploadID  uuid.UUID
+	OwnerID   uuid.UUID
+	Offset    int64
+	Length    int64
+	Metadata  string
+	HashState []byte
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	Status    UploadStatus
+	Finalized bool
+}
+
+type TusSegment struct {
+	UploadID   uuid.UUID
+	ByteOffset int64
+	ByteSize   int64
+	ObjectKey  string
+}
+
+type ErrUploadOffsetMismatch struct {
+	UploadID uuid.UUID
+	Offset   int64
+}
+
+func (e ErrUploadOffsetMismatch) Error() string {
+	return fmt.Sprintf("upload offset mismatch: %s, %d", e.UploadID, e.Offset)
+}
+
+func CreateTusUpload(ctx context.Context, ownerID uuid.UUID, id uuid.UUID, size int, mimeType string, metadata string, expiresAt time.Time) error {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return errors.New("connection not found in context")
+	}
+	_, err := conn.Exec(ctx, "CALL upload.create_tus_upload($1, $2, $3, $4, $5, $6)", id, ownerID, size, mimeType, metadata, expiresAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key value violates unique constraint \"uploads_pkey\"") {
+			return ErrUploadAlreadyExists{UploadID: id}
+		}
+		if strings.Contains(err.Error(), "Quota exceeded:") {
+			return quotaExceeded(err, ownerID)
+		}
+		return err
+	}
+	return nil
+}
+
+// GetTusUpload returns a tus upload belonging to ownerID. Uploads of other
+// users are reported as not found.
+func GetTusUpload(ctx context.Context, ownerID uuid.UUID, uploadID uuid.UUID) (*TusUpload, error) {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return nil, errors.New("connection not found in context")
+	}
+	var upload TusUpload
+	var size int
+	row := conn.QueryRow(ctx, "SELECT t.upload_id, u.owner_id, t.upload_offset, u.size, t.metadata, t.hash_state, t.expires_at, t.created_at, u.status, o.upload_id IS NOT NULL FROM upload.tus_uploads t JOIN upload.uploads u ON u.id = t.upload_id LEFT JOIN upload.objects o ON o.upload_id = t.upload_id WHERE t.upload_id = $1 AND u.owner_id = $2", uploadID, ownerID)
+	err := row.Scan(&upload.UploadID, &upload.OwnerID, &upload.Offset, &size, &upload.Metadata, &upload.HashState, &upload.ExpiresAt, &upload.CreatedAt, &upload.Status, &upload.Finalized)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			return nil, ErrUploadNotFound{UploadID: uploadID}
+		}
+		return nil, err
+	}
+	upload.Length = int64(size)
+	return &upload, nil
+}
+
+// AppendTusSegment records a segment stored at the upload's current offset,
+// advances the offset past it and pushes the expiry of the upload back to
+// expiresAt. It fails with ErrUploadOffsetMismatch if the offset has moved on,
+// e.g. because of a concurrent request.
+func AppendTusSegment(ctx context.Context, segment TusSegment, hashState []byte, expiresAt time.Time) error {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return errors.New("connection not found in context")
+	}
+	_, err := conn.Exec(ctx, "CALL upload.append_tus_segment($1, $2, $3, $4, $5, $6)", segment.UploadID, segment.ByteOffset, segment.ByteSize, segment.ObjectKey, hashState, expiresAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "Upload offset mismatch:") || strings.Contains(err.Error(), "duplicate key value violates unique constraint \"tus_segments_pkey\"") {
+			return ErrUploadOffsetMismatch{UploadID: segment.UploadID, Offset: segment.ByteOffset}
+		}
+		// The garbage collector is deleting the upload
+		if strings.Contains(err.Error(), "Upload failed:") {
+			return ErrUploadNotFound{UploadID: segment.UploadID}
+		}
+		return err
+	}
+	return nil
+}
+
+func GetTusSegments(ctx context.Context, uploadID uuid.UUID) ([]TusSegment, error) {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return nil, errors.New("connection not found in context")
+	}
+	rows, err := conn.Query(ctx, "SELECT upload_id, byte_offset, byte_size, object_key FROM upload.tus_segments WHERE upload_id = $1 ORDER BY byte_offset", uploadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	segments := []TusSegment{}
+	for rows.Next() {
+		var segment TusSegment
+		err := rows.Scan(&segment.UploadID, &segment.ByteOffset, &segment.ByteSize, &segment.ObjectKey)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment)
+	}
+	return segments, rows.Err()
+}
+
+func GetTusSegmentObjectKeys(ctx context.Context, uploadID uuid.UUID) ([]string, error) {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return nil, errors.New("connection not found in context")
+	}
+	rows, err := conn.Query(ctx, "SELECT upload.get_tus_segment_object_keys($1)", uploadID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// codeSerializationFailure is the SQLSTATE CockroachDB returns when a
+// transaction conflicts with another and must be retried.
+const codeSerializationFailure = "40001"
+
+const (
+	// maxTxAttempts is how many times RunInTx runs a transaction that keeps
+	// failing with serialization failures.
+	maxTxAttempts = 10
+	// txRetryDelay is the delay before the first retry, which doubles with
+	// each further retry up to maxTxRetryDelay.
+	txRetryDelay    = 10 * time.Millisecond
+	maxTxRetryDelay = time.Second
+)
+
+// IsRetryable reports whether err is a serialization failure, after which the
+// transaction can be retried.
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == codeSerializationFailure
+}
+
+// retryDelay returns how long to wait before retrying after the given
+// attempt. The delay is jittered so that conflicting transactions do not
+// retry in lockstep.
+func retryDelay(attempt int) time.Duration {
+	delay := min(txRetryDelay<<(attempt-1), maxTxRetryDelay)
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// RunInTx runs fn in a transaction on the pool in ctx, committing it if fn
+// succeeds and rolling it back otherwise. The context passed to fn carries
+// the transaction, so db functions called with it use the transaction.
+//
+// If fn or the commit fails with a serialization failure, the transaction is
+// rolled back to the cockroach_restart savepoint and fn is run again after a
+// backoff, so fn must be safe to run more than once. If ctx already carries a
+// transaction, fn runs in it and retries are left to the outermost RunInTx.
+func RunInTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey).(*pgx.Tx); ok {
+		return fn(ctx)
+	}
+	pool, ok := ctx.Value(connPoolKey).(*pgxpool.Pool)
+	if !ok {
+		return errors.New("connection not found in context")
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	// Rolling back a committed transaction does nothing
+	defer tx.Rollback(context.WithoutCancel(ctx))
+	if _, err := tx.Exec(ctx, "SAVEPOINT cockroach_restart"); err != nil {
+		return err
+	}
+
+	txCtx := WithTx(ctx, &tx)
+	for attempt := 1; ; attempt++ {
+		err := fn(txCtx)
+		if err == nil {
+			// CockroachDB commits when the savepoint is released, so
+			// conflicts surface here
+			_, err = tx.Exec(ctx, "RELEASE SAVEPOINT cockroach_restart")
+		}
+		if err == nil {
+			return tx.Commit(ctx)
+		}
+		if !IsRetryable(err) {
+			return err
+		}
+		if attempt == maxTxAttempts {
+			return fmt.Errorf("transaction failed after %d attempts: %w", attempt, err)
+		}
+		if _, err := tx.Exec(ctx, "ROLLBACK TO SAVEPOINT cockroach_restart"); err != nil {
+			return fmt.Errorf("restarting transaction: %w", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retryDelay(attempt)):
+		}
+	}
+}
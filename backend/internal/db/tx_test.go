@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIsRetryable(t *testing.T) {
+	t.Parallel()
+	serialization := &pgconn.PgError{Code: codeSerializationFailure}
+	if !IsRetryable(serialization) {
+		t.Fatalf("Expected a serialization failure to be retryable")
+	}
+	if !IsRetryable(fmt.Errorf("updating part: %w", serialization)) {
+		t.Fatalf("Expected a wrapped serialization failure to be retryable")
+	}
+	if IsRetryable(&pgconn.PgError{Code: "23505"}) {
+		t.Fatalf("Expected a unique violation not to be retryable")
+	}
+	if IsRetryable(errors.New("boom")) {
+		t.Fatalf("Expected other errors not to be retryable")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	t.Parallel()
+	for attempt := 1; attempt <= maxTxAttempts; attempt++ {
+		ceiling := min(txRetryDelay<<(attempt-1), maxTxRetryDelay)
+		delay := retryDelay(attempt)
+		if delay < ceiling/2 || delay > ceiling {
+			t.Fatalf("Expected delay of attempt %d between %s and %s, got %s", attempt, ceiling/2, ceiling, delay)
+		}
+	}
+}
+
+func TestRunInTx_Retry(t *testing.T) {
+	t.Parallel()
+	pool, cleanup, err := InitDBPool(testConfig(t))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer cleanup()
+	ctx := WithConnPool(context.Background(), pool)
+
+	attempts := 0
+	err = RunInTx(ctx, func(ctx context.Context) error {
+		attempts++
+		outer, ok := ctx.Value(txKey).(*pgx.Tx)
+		if !ok {
+			t.Fatalf("Expected a transaction in the context")
+		}
+		// Nested calls reuse the outer transaction
+		err := RunInTx(ctx, func(ctx context.Context) error {
+			if inner, _ := ctx.Value(txKey).(*pgx.Tx); inner != outer {
+				t.Fatalf("Expected the nested call to reuse the outer transaction")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if attempts < 3 {
+			return &pgconn.PgError{Code: codeSerializationFailure}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestRunInTx_Error(t *testing.T) {
+	t.Parallel()
+	pool, cleanup, err := InitDBPool(testConfig(t))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer cleanup()
+	ctx := WithConnPool(context.Background(), pool)
+
+	boom := errors.New("boom")
+	attempts := 0
+	err = RunInTx(ctx, func(ctx context.Context) error {
+		attempts++
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("Expected boom, got %v", err)
+	}
+	if attempts != 1 {
+		t.Fatalf("Expected 1 attempt, got %d", attempts)
+	}
+
+	// Every attempt fails, so the retries run out
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	attempts = 0
+	err = RunInTx(ctx, func(ctx context.Context) error {
+		attempts++
+		return &pgconn.PgError{Code: codeSerializationFailure}
+	})
+	if !IsRetryable(err) || attempts != maxTxAttempts {
+		t.Fatalf("Expected a serialization failure after %d attempts, got %v after %d", maxTxAttempts, err, attempts)
+	}
+}
+
+func TestRunInTx_NoConnection(t *testing.T) {
+	t.Parallel()
+	err := RunInTx(context.Background(), func(ctx context.Context) error {
+		t.Fatalf("Expected fn not to run without a connection")
+		return nil
+	})
+	if err == nil {
+		t.Fatalf("Expected an error without a connection")
+	}
+}
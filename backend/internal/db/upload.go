@@ -8,19 +8,54 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 type Upload struct {
 	ID         uuid.UUID
+	OwnerID    *uuid.UUID
 	PartsCount int
 	Size       int
 	MimeType   string
 	Status     UploadStatus
 	CreatedAt  time.Time
+
+	// Set once the parts have been assembled into the final object
+	ObjectKey   *string
+	Sha256      *[]byte
+	FinalizedAt *time.Time
+}
+
+// FinalObjectKey is the key of the object assembled from the parts of an
+// upload. Part keys, generated by upload.create_new_upload, add the part number.
+func FinalObjectKey(uploadID uuid.UUID) string {
+	return "upload-" + uploadID.String()
+}
+
+// Finalized reports whether the final object of the upload has been recorded.
+func (u Upload) Finalized() bool {
+	return u.ObjectKey != nil
+}
+
+// PartRange returns the byte offset and size of a part. An upload is split
+// into PartsCount parts whose sizes differ by at most one byte, with the larger
+// parts first.
+func (u Upload) PartRange(partNumber int) (offset int64, size int64) {
+	if u.PartsCount <= 0 || partNumber < 0 || partNumber >= u.PartsCount {
+		return 0, 0
+	}
+	base := int64(u.Size) / int64(u.PartsCount)
+	remainder := int64(u.Size) % int64(u.PartsCount)
+	n := int64(partNumber)
+	offset = n*base + min(n, remainder)
+	size = base
+	if n < remainder {
+		size++
+	}
+	return offset, size
 }
 
 type Part struct {
-	ID         uuid.UUID
 	UploadID   uuid.UUID
 	PartNumber int
 	Status     PartStatus
@@ -74,37 +109,83 @@ func (e ErrUploadNotFound) Error() string {
 	return fmt.Sprintf("upload not found: %s", e.UploadID)
 }
 
-func CreateUpload(ctx context.Context, id uuid.UUID, partsCount int, size int, mimeType string) error {
+type ErrUploadNotCompleted struct {
+	UploadID uuid.UUID
+}
+
+func (e ErrUploadNotCompleted) Error() string {
+	return fmt.Sprintf("upload not completed: %s", e.UploadID)
+}
+
+type ErrUploadAlreadyFinalized struct {
+	UploadID uuid.UUID
+}
+
+func (e ErrUploadAlreadyFinalized) Error() string {
+	return fmt.Sprintf("upload already finalized: %s", e.UploadID)
+}
+
+func CreateUpload(ctx context.Context, ownerID uuid.UUID, id uuid.UUID, partsCount int, size int, mimeType string) error {
 	conn, ok := GetConn(ctx)
 	if !ok {
 		return errors.New("connection not found in context")
 	}
-	_, err := conn.Exec(ctx, "CALL upload.create_new_upload($1, $2, $3, $4)", id, partsCount, size, mimeType)
+	_, err := conn.Exec(ctx, "CALL upload.create_new_upload($1, $2, $3, $4, $5)", id, ownerID, partsCount, size, mimeType)
 	if err != nil {
 		if strings.Contains(err.Error(), "duplicate key value violates unique constraint \"uploads_pkey\"") {
 			return ErrUploadAlreadyExists{UploadID: id}
 		}
+		if strings.Contains(err.Error(), "Quota exceeded:") {
+			return quotaExceeded(err, ownerID)
+		}
 		return err
 	}
 	return nil
 }
 
 func UpdateUploadPart(ctx context.Context, newPart Part) error {
+	// Parts of an upload finishing together all update its row, so this
+	// runs in a transaction that is retried when they conflict
+	return RunInTx(ctx, func(ctx context.Context) error {
+		conn, ok := GetConn(ctx)
+		if !ok {
+			return errors.New("connection not found in context")
+		}
+		_, err := conn.Exec(ctx, "CALL upload.update_part($1, $2, $3, $4, $5, $6, $7)", newPart.UploadID, newPart.PartNumber, newPart.Status, newPart.ObjectKey, newPart.ByteOffset, newPart.ByteSize, newPart.Sha256)
+		if err != nil {
+			if strings.Contains(err.Error(), "Part status not found:") {
+				return ErrPartNotFound{UploadID: newPart.UploadID, PartNumber: newPart.PartNumber}
+			}
+			// The garbage collector is deleting the upload
+			if strings.Contains(err.Error(), "Upload failed:") {
+				return ErrUploadNotFound{UploadID: newPart.UploadID}
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+// DeleteUpload deletes an upload belonging to ownerID. Uploads of other users
+// are reported as not found.
+func DeleteUpload(ctx context.Context, ownerID uuid.UUID, uploadID uuid.UUID) error {
 	conn, ok := GetConn(ctx)
 	if !ok {
 		return errors.New("connection not found in context")
 	}
-	_, err := conn.Exec(ctx, "CALL upload.update_part($1, $2, $3, $4, $5, $6, $7)", newPart.UploadID, newPart.PartNumber, newPart.Status, newPart.ObjectKey, newPart.ByteOffset, newPart.ByteSize, newPart.Sha256)
+	_, err := conn.Exec(ctx, "CALL upload.delete_owned_upload($1, $2)", uploadID, ownerID)
 	if err != nil {
-		if strings.Contains(err.Error(), "Part status not found:") {
-			return ErrPartNotFound{UploadID: newPart.UploadID, PartNumber: newPart.PartNumber}
+		if strings.Contains(err.Error(), "Upload not found:") {
+			return ErrUploadNotFound{UploadID: uploadID}
 		}
 		return err
 	}
 	return nil
 }
 
-func DeleteUpload(ctx context.Context, uploadID uuid.UUID) error {
+// PurgeUpload deletes an upload whoever owns it, for cleaning up after
+// abandoned uploads.
+func PurgeUpload(ctx context.Context, uploadID uuid.UUID) error {
 	conn, ok := GetConn(ctx)
 	if !ok {
 		return errors.New("connection not found in context")
@@ -119,29 +200,65 @@ func DeleteUpload(ctx context.Context, uploadID uuid.UUID) error {
 	return nil
 }
 
-func GetUpload(ctx context.Context, uploadID uuid.UUID) (*Upload, error) {
+// FinalizeUpload records the object assembled from the parts of a completed
+// upload.
+func FinalizeUpload(ctx context.Context, uploadID uuid.UUID, objectKey string, size int64, sha256 []byte) error {
 	conn, ok := GetConn(ctx)
 	if !ok {
-		return nil, errors.New("connection not found in context")
+		return errors.New("connection not found in context")
 	}
+	_, err := conn.Exec(ctx, "CALL upload.finalize_upload($1, $2, $3, $4)", uploadID, objectKey, size, sha256)
+	if err != nil {
+		if strings.Contains(err.Error(), "Upload not found:") {
+			return ErrUploadNotFound{UploadID: uploadID}
+		}
+		if strings.Contains(err.Error(), "Upload not completed:") {
+			return ErrUploadNotCompleted{UploadID: uploadID}
+		}
+		if strings.Contains(err.Error(), "Upload already finalized:") || strings.Contains(err.Error(), "duplicate key value violates unique constraint \"objects_pkey\"") {
+			return ErrUploadAlreadyFinalized{UploadID: uploadID}
+		}
+		return err
+	}
+	return nil
+}
+
+const selectUpload = "SELECT u.id, u.owner_id, u.created_at, u.status, u.parts_count, u.size, u.mime_type, o.object_key, o.sha256, o.created_at FROM upload.uploads u LEFT JOIN upload.objects o ON o.upload_id = u.id"
+
+func scanUpload(row pgx.Row) (*Upload, error) {
 	var upload Upload
-	row := conn.QueryRow(ctx, "SELECT id, created_at, status, parts_count, size, mime_type FROM upload.uploads WHERE id = $1", uploadID)
-	err := row.Scan(&upload.ID, &upload.CreatedAt, &upload.Status, &upload.PartsCount, &upload.Size, &upload.MimeType)
+	err := row.Scan(&upload.ID, &upload.OwnerID, &upload.CreatedAt, &upload.Status, &upload.PartsCount, &upload.Size, &upload.MimeType, &upload.ObjectKey, &upload.Sha256, &upload.FinalizedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// GetUpload returns an upload belonging to ownerID. Uploads of other users are
+// reported as not found.
+func GetUpload(ctx context.Context, ownerID uuid.UUID, uploadID uuid.UUID) (*Upload, error) {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return nil, errors.New("connection not found in context")
+	}
+	upload, err := scanUpload(conn.QueryRow(ctx, selectUpload+" WHERE u.id = $1 AND u.owner_id = $2", uploadID, ownerID))
 	if err != nil {
 		if strings.Contains(err.Error(), "no rows in result set") {
 			return nil, ErrUploadNotFound{UploadID: uploadID}
 		}
 		return nil, err
 	}
-	return &upload, nil
+	return upload, nil
 }
 
-func GetUploadParts(ctx context.Context, uploadID uuid.UUID) ([]Part, error) {
+// GetUploadParts returns the parts of an upload belonging to ownerID, in order.
+// Uploads of other users are reported as not found.
+func GetUploadParts(ctx context.Context, ownerID uuid.UUID, uploadID uuid.UUID) ([]Part, error) {
 	conn, ok := GetConn(ctx)
 	if !ok {
 		return nil, errors.New("connection not found in context")
 	}
-	rows, err := conn.Query(ctx, "SELECT id, upload_id, part_number, status, object_key, created_at, uploaded_at, byte_offset, byte_size, sha256 FROM upload.parts WHERE upload_id = $1", uploadID)
+	rows, err := conn.Query(ctx, "SELECT p.upload_id, p.part_number, p.status, p.object_key, p.created_at, p.uploaded_at, p.byte_offset, p.byte_size, p.sha256 FROM upload.parts p JOIN upload.uploads u ON u.id = p.upload_id WHERE p.upload_id = $1 AND u.owner_id = $2 ORDER BY p.part_number", uploadID, ownerID)
 	if err != nil {
 		return nil, err
 	}
@@ -149,7 +266,7 @@ func GetUploadParts(ctx context.Context, uploadID uuid.UUID) ([]Part, error) {
 	parts := []Part{}
 	for rows.Next() {
 		var part Part
-		err := rows.Scan(&part.ID, &part.UploadID, &part.PartNumber, &part.Status, &part.ObjectKey, &part.CreatedAt, &part.UploadedAt, &part.ByteOffset, &part.ByteSize, &part.Sha256)
+		err := rows.Scan(&part.UploadID, &part.PartNumber, &part.Status, &part.ObjectKey, &part.CreatedAt, &part.UploadedAt, &part.ByteOffset, &part.ByteSize, &part.Sha256)
 		if err != nil {
 			return nil, err
 		}
@@ -160,3 +277,98 @@ func GetUploadParts(ctx context.Context, uploadID uuid.UUID) ([]Part, error) {
 	}
 	return parts, nil
 }
+
+func GetUploadPartObjectKeys(ctx context.Context, uploadID uuid.UUID) ([]string, error) {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return nil, errors.New("connection not found in context")
+	}
+	rows, err := conn.Query(ctx, "SELECT upload.get_upload_part_object_keys($1)", uploadID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// staleUpload is the condition on upload u of ListStaleUploads and
+// ClaimStaleUpload, with the cutoff as $1. tus uploads go stale once they
+// expire, other uploads once they have seen no activity since the cutoff.
+const staleUpload = `NOT EXISTS (SELECT 1 FROM upload.objects o WHERE o.upload_id = u.id)
+	AND COALESCE((SELECT t.expires_at < now() FROM upload.tus_uploads t WHERE t.upload_id = u.id), u.updated_at < $1)`
+
+// ListStaleUploads returns up to limit uploads that were never finalized and
+// have gone stale, least recently active first. Uploads without activity
+// since cutoff are stale, except for tus uploads, which are stale once they
+// expire.
+func ListStaleUploads(ctx context.Context, cutoff time.Time, limit int) ([]uuid.UUID, error) {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return nil, errors.New("connection not found in context")
+	}
+	rows, err := conn.Query(ctx, `SELECT u.id FROM upload.uploads u
+		WHERE `+staleUpload+`
+		ORDER BY u.updated_at
+		LIMIT $2`, cutoff, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+}
+
+// ClaimStaleUpload marks an upload listed by ListStaleUploads as failed if it
+// is still stale, after which its parts can no longer be updated and it can no
+// longer be finalized. It reports whether the upload was claimed, which it is
+// not if it has seen activity or been finalized in the meantime.
+func ClaimStaleUpload(ctx context.Context, uploadID uuid.UUID, cutoff time.Time) (bool, error) {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return false, errors.New("connection not found in context")
+	}
+	tag, err := conn.Exec(ctx, `UPDATE upload.uploads u SET status = 'failed'
+		WHERE u.id = $2 AND `+staleUpload, cutoff, uploadID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// TouchUpload records activity on an upload about to be finalized, so that it
+// does not go stale meanwhile. It fails with ErrUploadNotFound if the upload
+// has been claimed by ClaimStaleUpload.
+func TouchUpload(ctx context.Context, uploadID uuid.UUID) error {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return errors.New("connection not found in context")
+	}
+	_, err := conn.Exec(ctx, "CALL upload.touch_upload($1)", uploadID)
+	if err != nil {
+		if strings.Contains(err.Error(), "Upload not found:") {
+			return ErrUploadNotFound{UploadID: uploadID}
+		}
+		return err
+	}
+	return nil
+}
+
+// CountUploadsByStatus counts the uploads of all owners in each status.
+func CountUploadsByStatus(ctx context.Context) (map[UploadStatus]int64, error) {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return nil, errors.New("connection not found in context")
+	}
+	rows, err := conn.Query(ctx, "SELECT status, COUNT(*) FROM upload.uploads GROUP BY status")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := map[UploadStatus]int64{}
+	for rows.Next() {
+		var status UploadStatus
+		var count int64
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
+	}
+	return counts, rows.Err()
+}
@@ -11,7 +11,7 @@ import (
 )
 
 func SetupTest(t *testing.T) (context.Context, *pgx.Tx, func()) {
-	dbpool, cleanup, err := InitDBPool()
+	dbpool, cleanup, err := InitDBPool(testConfig(t))
 	if err != nil {
 		t.Fatalf("Failed to initialize database: %v", err)
 	}
@@ -26,13 +26,22 @@ func SetupTest(t *testing.T) (context.Context, *pgx.Tx, func()) {
 	}
 }
 
+func createTestUser(ctx context.Context, t *testing.T) uuid.UUID {
+	user, err := GetOrCreateUser(ctx, "test-"+uuid.NewString())
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	return user.ID
+}
+
 func TestCreateUpload(t *testing.T) {
 	// Initialize the database connection
 	id := uuid.New()
 	ctx, tx, cleanup := SetupTest(t)
 	defer cleanup()
+	owner := createTestUser(ctx, t)
 
-	err := CreateUpload(ctx, id, 1, 1024, "image/jpeg")
+	err := CreateUpload(ctx, owner, id, 1, 1024, "image/jpeg")
 	if err != nil {
 		t.Fatalf("Failed to create upload: %v", err)
 	}
@@ -45,8 +54,9 @@ func TestCreateUpload(t *testing.T) {
 		PartsCount int
 		Size       int
 		MimeType   string
+		OwnerID    uuid.UUID
 	}
-	err = (*tx).QueryRow(context.Background(), "(SELECT * FROM upload.uploads WHERE id = $1)", id).Scan(&upload.ID, &upload.CreatedAt, &upload.Status, &upload.PartsCount, &upload.Size, &upload.MimeType)
+	err = (*tx).QueryRow(context.Background(), "SELECT id, created_at, status, parts_count, size, mime_type, owner_id FROM upload.uploads WHERE id = $1", id).Scan(&upload.ID, &upload.CreatedAt, &upload.Status, &upload.PartsCount, &upload.Size, &upload.MimeType, &upload.OwnerID)
 	if err != nil {
 		t.Fatalf("Failed to check if upload exists: %v", err)
 	}
@@ -65,6 +75,9 @@ func TestCreateUpload(t *testing.T) {
 	if upload.MimeType != "image/jpeg" {
 		t.Fatalf("Expected mime type to be image/jpeg, got %s", upload.MimeType)
 	}
+	if upload.OwnerID != owner {
+		t.Fatalf("Expected owner to be %v, got %v", owner, upload.OwnerID)
+	}
 
 	// Check if the upload parts exist in the database
 	var partsCount int
@@ -82,14 +95,15 @@ func TestCreateUpload_Duplicate(t *testing.T) {
 	id := uuid.New()
 	ctx, _, cleanup := SetupTest(t)
 	defer cleanup()
+	owner := createTestUser(ctx, t)
 
-	err := CreateUpload(ctx, id, 1, 1024, "image/jpeg")
+	err := CreateUpload(ctx, owner, id, 1, 1024, "image/jpeg")
 	if err != nil {
 		t.Fatalf("Failed to create upload: %v", err)
 	}
 
 	// Attempt to create the same upload again
-	err = CreateUpload(ctx, id, 1, 1024, "image/jpeg")
+	err = CreateUpload(ctx, owner, id, 1, 1024, "image/jpeg")
 	var target ErrUploadAlreadyExists
 	if !errors.As(err, &target) {
 		t.Fatalf("Expected ErrUploadAlreadyExists, got %v", err)
@@ -100,9 +114,10 @@ func TestUpdateUploadPart(t *testing.T) {
 	id := uuid.New()
 	ctx, tx, cleanup := SetupTest(t)
 	defer cleanup()
+	owner := createTestUser(ctx, t)
 
 	// Call CreateUpload() with 2 parts
-	err := CreateUpload(ctx, id, 2, 2048, "application/pdf")
+	err := CreateUpload(ctx, owner, id, 2, 2048, "application/pdf")
 	if err != nil {
 		t.Fatalf("Failed to create upload: %v", err)
 	}
@@ -184,8 +199,9 @@ func TestUpdateUploadPart_UploadedNoInfo(t *testing.T) {
 	id := uuid.New()
 	ctx, _, cleanup := SetupTest(t)
 	defer cleanup()
+	owner := createTestUser(ctx, t)
 	// Create upload with 1 part
-	err := CreateUpload(ctx, id, 1, 1024, "image/jpeg")
+	err := CreateUpload(ctx, owner, id, 1, 1024, "image/jpeg")
 	if err != nil {
 		t.Fatalf("Failed to create upload: %v", err)
 	}
@@ -205,6 +221,42 @@ func TestUpdateUploadPart_UploadedNoInfo(t *testing.T) {
 
 }
 
+func TestUpdateUploadPart_ZeroOffset(t *testing.T) {
+	id := uuid.New()
+	ctx, tx, cleanup := SetupTest(t)
+	defer cleanup()
+	owner := createTestUser(ctx, t)
+	err := CreateUpload(ctx, owner, id, 1, 1024, "image/jpeg")
+	if err != nil {
+		t.Fatalf("Failed to create upload: %v", err)
+	}
+
+	// The first part starts at byte 0
+	byteOffset := int64(0)
+	byteSize := int64(1024)
+	sha256 := []byte("1234567890")
+	err = UpdateUploadPart(ctx, Part{
+		UploadID:   id,
+		PartNumber: 0,
+		Status:     PartStatusUploaded,
+		ByteOffset: &byteOffset,
+		ByteSize:   &byteSize,
+		Sha256:     &sha256,
+	})
+	if err != nil {
+		t.Fatalf("Failed to update part 0 at byte offset 0: %v", err)
+	}
+
+	var uploadedAt *time.Time
+	err = (*tx).QueryRow(context.Background(), "SELECT uploaded_at FROM upload.parts WHERE upload_id = $1 AND part_number = 0", id).Scan(&uploadedAt)
+	if err != nil {
+		t.Fatalf("Failed to check part uploaded_at: %v", err)
+	}
+	if uploadedAt == nil {
+		t.Fatalf("Expected uploaded_at to be set")
+	}
+}
+
 func TestUpdateUploadPartStatus_NoStatusToUpdate(t *testing.T) {
 	id := uuid.New()
 	ctx, _, cleanup := SetupTest(t)
@@ -231,15 +283,16 @@ func TestDeleteUpload(t *testing.T) {
 	id := uuid.New()
 	ctx, tx, cleanup := SetupTest(t)
 	defer cleanup()
+	owner := createTestUser(ctx, t)
 
 	// Create upload
-	err := CreateUpload(ctx, id, 2, 1024, "application/octet-stream")
+	err := CreateUpload(ctx, owner, id, 2, 1024, "application/octet-stream")
 	if err != nil {
 		t.Fatalf("Failed to create upload: %v", err)
 	}
 
 	// Delete upload
-	err = DeleteUpload(ctx, id)
+	err = DeleteUpload(ctx, owner, id)
 	if err != nil {
 		t.Fatalf("Failed to delete upload: %v", err)
 	}
@@ -269,9 +322,10 @@ func TestDeleteUpload_NotFound(t *testing.T) {
 	id := uuid.New()
 	ctx, _, cleanup := SetupTest(t)
 	defer cleanup()
+	owner := createTestUser(ctx, t)
 
 	// Attempt to delete a non-existent upload
-	err := DeleteUpload(ctx, id)
+	err := DeleteUpload(ctx, owner, id)
 	var target ErrUploadNotFound
 	if !errors.As(err, &target) {
 		t.Fatalf("Expected ErrUploadNotFound, got %v", err)
@@ -282,15 +336,16 @@ func TestGetUpload(t *testing.T) {
 	id := uuid.New()
 	ctx, _, cleanup := SetupTest(t)
 	defer cleanup()
+	owner := createTestUser(ctx, t)
 
 	// Create upload
-	err := CreateUpload(ctx, id, 2, 1024, "application/octet-stream")
+	err := CreateUpload(ctx, owner, id, 2, 1024, "application/octet-stream")
 	if err != nil {
 		t.Fatalf("Failed to create upload: %v", err)
 	}
 
 	// Get upload
-	upload, err := GetUpload(ctx, id)
+	upload, err := GetUpload(ctx, owner, id)
 	if err != nil {
 		t.Fatalf("Failed to get upload: %v", err)
 	}
@@ -320,27 +375,79 @@ func TestGetUpload_NotFound(t *testing.T) {
 	id := uuid.New()
 	ctx, _, cleanup := SetupTest(t)
 	defer cleanup()
+	owner := createTestUser(ctx, t)
 	// Attempt to get a non-existent upload
-	_, err := GetUpload(ctx, id)
+	_, err := GetUpload(ctx, owner, id)
 	var target ErrUploadNotFound
 	if !errors.As(err, &target) {
 		t.Fatalf("Expected ErrUploadNotFound, got %v", err)
 	}
 }
 
+func TestUpload_OtherOwner(t *testing.T) {
+	id := uuid.New()
+	ctx, _, cleanup := SetupTest(t)
+	defer cleanup()
+	owner := createTestUser(ctx, t)
+	other := createTestUser(ctx, t)
+
+	err := CreateUpload(ctx, owner, id, 2, 1024, "application/octet-stream")
+	if err != nil {
+		t.Fatalf("Failed to create upload: %v", err)
+	}
+
+	// Other users cannot tell the upload exists
+	var target ErrUploadNotFound
+	_, err = GetUpload(ctx, other, id)
+	if !errors.As(err, &target) {
+		t.Fatalf("Expected ErrUploadNotFound from GetUpload, got %v", err)
+	}
+	_, err = GetUploadParts(ctx, other, id)
+	if !errors.As(err, &target) {
+		t.Fatalf("Expected ErrUploadNotFound from GetUploadParts, got %v", err)
+	}
+	err = DeleteUpload(ctx, other, id)
+	if !errors.As(err, &target) {
+		t.Fatalf("Expected ErrUploadNotFound from DeleteUpload, got %v", err)
+	}
+
+	if _, err := GetUpload(ctx, owner, id); err != nil {
+		t.Fatalf("Expected owner to still see the upload, got %v", err)
+	}
+}
+
+func TestGetOrCreateUser(t *testing.T) {
+	ctx, _, cleanup := SetupTest(t)
+	defer cleanup()
+
+	subject := "test-" + uuid.NewString()
+	user, err := GetOrCreateUser(ctx, subject)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	again, err := GetOrCreateUser(ctx, subject)
+	if err != nil {
+		t.Fatalf("Failed to get user: %v", err)
+	}
+	if again.ID != user.ID {
+		t.Fatalf("Expected the same user %v, got %v", user.ID, again.ID)
+	}
+}
+
 func TestGetUploadParts(t *testing.T) {
 	id := uuid.New()
 	ctx, _, cleanup := SetupTest(t)
 	defer cleanup()
+	owner := createTestUser(ctx, t)
 
 	// Create upload
-	err := CreateUpload(ctx, id, 6, 1024, "application/octet-stream")
+	err := CreateUpload(ctx, owner, id, 6, 1024, "application/octet-stream")
 	if err != nil {
 		t.Fatalf("Failed to create upload: %v", err)
 	}
 
 	// Get upload parts
-	parts, err := GetUploadParts(ctx, id)
+	parts, err := GetUploadParts(ctx, owner, id)
 	if err != nil {
 		t.Fatalf("Failed to get upload parts: %v", err)
 	}
@@ -355,11 +462,142 @@ func TestGetUploadParts_NotFound(t *testing.T) {
 	id := uuid.New()
 	ctx, _, cleanup := SetupTest(t)
 	defer cleanup()
+	owner := createTestUser(ctx, t)
 
 	// Attempt to get parts for a non-existent upload
-	parts, err := GetUploadParts(ctx, id)
+	parts, err := GetUploadParts(ctx, owner, id)
 	var target ErrUploadNotFound
 	if !errors.As(err, &target) {
 		t.Fatalf("Expected ErrUploadNotFound, got Err: %v, parts: %v", err, parts)
 	}
 }
+
+func TestUploadPartRange(t *testing.T) {
+	upload := Upload{PartsCount: 3, Size: 10}
+	expected := [][2]int64{{0, 4}, {4, 3}, {7, 3}}
+	for partNumber, want := range expected {
+		offset, size := upload.PartRange(partNumber)
+		if offset != want[0] || size != want[1] {
+			t.Fatalf("Expected part %d to be (%d, %d), got (%d, %d)", partNumber, want[0], want[1], offset, size)
+		}
+	}
+
+	if _, size := upload.PartRange(3); size != 0 {
+		t.Fatalf("Expected out of range part to be empty, got size %d", size)
+	}
+}
+
+func TestFinalizeUpload(t *testing.T) {
+	id := uuid.New()
+	ctx, _, cleanup := SetupTest(t)
+	defer cleanup()
+	owner := createTestUser(ctx, t)
+
+	err := CreateUpload(ctx, owner, id, 1, 1024, "image/jpeg")
+	if err != nil {
+		t.Fatalf("Failed to create upload: %v", err)
+	}
+
+	// Finalizing before every part is uploaded fails
+	sha256 := []byte("1234567890")
+	err = FinalizeUpload(ctx, id, "upload-"+id.String(), 1024, sha256)
+	var notCompleted ErrUploadNotCompleted
+	if !errors.As(err, &notCompleted) {
+		t.Fatalf("Expected ErrUploadNotCompleted, got %v", err)
+	}
+
+	byteOffset := int64(0)
+	byteSize := int64(1024)
+	err = UpdateUploadPart(ctx, Part{
+		UploadID:   id,
+		PartNumber: 0,
+		Status:     PartStatusUploaded,
+		ByteOffset: &byteOffset,
+		ByteSize:   &byteSize,
+		Sha256:     &sha256,
+	})
+	if err != nil {
+		t.Fatalf("Failed to update part 0 status: %v", err)
+	}
+
+	err = FinalizeUpload(ctx, id, "upload-"+id.String(), 1024, sha256)
+	if err != nil {
+		t.Fatalf("Failed to finalize upload: %v", err)
+	}
+	upload, err := GetUpload(ctx, owner, id)
+	if err != nil {
+		t.Fatalf("Failed to get upload: %v", err)
+	}
+	if !upload.Finalized() || *upload.ObjectKey != "upload-"+id.String() {
+		t.Fatalf("Expected upload to be finalized with its object key, got %v", upload.ObjectKey)
+	}
+	if upload.FinalizedAt == nil {
+		t.Fatalf("Expected finalized_at to be set")
+	}
+
+	// Finalizing twice fails
+	err = FinalizeUpload(ctx, id, "upload-"+id.String(), 1024, sha256)
+	var alreadyFinalized ErrUploadAlreadyFinalized
+	if !errors.As(err, &alreadyFinalized) {
+		t.Fatalf("Expected ErrUploadAlreadyFinalized, got %v", err)
+	}
+}
+
+func TestShareAvailable(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	maxDownloads := 2
+
+	if !(Share{}).Available(now) {
+		t.Fatalf("Expected share without limits to be available")
+	}
+	if (Share{ExpiresAt: &past}).Available(now) {
+		t.Fatalf("Expected expired share to be unavailable")
+	}
+	if (Share{MaxDownloads: &maxDownloads, DownloadCount: 2}).Available(now) {
+		t.Fatalf("Expected share at its download limit to be unavailable")
+	}
+}
+
+func TestCreateUpload_QuotaExceeded(t *testing.T) {
+	ctx, _, cleanup := SetupTest(t)
+	defer cleanup()
+	owner := createTestUser(ctx, t)
+
+	maxTotalBytes := int64(2048)
+	maxFileSize := int64(1024)
+	maxConcurrentUploads := 1
+	err := SetQuota(ctx, owner, Quota{MaxTotalBytes: &maxTotalBytes, MaxFileSize: &maxFileSize, MaxConcurrentUploads: &maxConcurrentUploads})
+	if err != nil {
+		t.Fatalf("Failed to set quota: %v", err)
+	}
+
+	var target ErrQuotaExceeded
+	err = CreateUpload(ctx, owner, uuid.New(), 1, 1025, "image/jpeg")
+	if !errors.As(err, &target) || target.Limit != QuotaLimitFileSize {
+		t.Fatalf("Expected ErrQuotaExceeded for %s, got %v", QuotaLimitFileSize, err)
+	}
+	err = CreateUpload(ctx, owner, uuid.New(), 1, 1024, "image/jpeg")
+	if err != nil {
+		t.Fatalf("Failed to create upload: %v", err)
+	}
+	err = CreateUpload(ctx, owner, uuid.New(), 1, 1024, "image/jpeg")
+	if !errors.As(err, &target) || target.Limit != QuotaLimitConcurrentUploads {
+		t.Fatalf("Expected ErrQuotaExceeded for %s, got %v", QuotaLimitConcurrentUploads, err)
+	}
+
+	usage, err := GetUsage(ctx, owner)
+	if err != nil {
+		t.Fatalf("Failed to get usage: %v", err)
+	}
+	if usage.TotalBytes != 1024 || usage.ConcurrentUploads != 1 {
+		t.Fatalf("Expected 1024 bytes in 1 concurrent upload, got %+v", usage)
+	}
+	quota, err := GetQuota(ctx, owner)
+	if err != nil {
+		t.Fatalf("Failed to get quota: %v", err)
+	}
+	if quota.MaxFileSize == nil || *quota.MaxFileSize != maxFileSize {
+		t.Fatalf("Expected max file size %d, got %v", maxFileSize, quota.MaxFileSize)
+	}
+}
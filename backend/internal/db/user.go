@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// User owns uploads. It is identified by the subject it authenticates as.
+type User struct {
+	ID        uuid.UUID
+	Subject   string
+	CreatedAt time.Time
+}
+
+func GetUserBySubject(ctx context.Context, subject string) (*User, error) {
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return nil, errors.New("connection not found in context")
+	}
+	var user User
+	row := conn.QueryRow(ctx, "SELECT id, subject, created_at FROM auth.users WHERE subject = $1", subject)
+	err := row.Scan(&user.ID, &user.Subject, &user.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetOrCreateUser returns the user for subject, creating it if it is new.
+func GetOrCreateUser(ctx context.Context, subject string) (*User, error) {
+	user, err := GetUserBySubject(ctx, subject)
+	if err == nil {
+		return user, nil
+	} else if !strings.Contains(err.Error(), "no rows in result set") {
+		return nil, err
+	}
+
+	conn, ok := GetConn(ctx)
+	if !ok {
+		return nil, errors.New("connection not found in context")
+	}
+	// Another request may create the user first, in which case it is read
+	// back instead
+	_, err = conn.Exec(ctx, "INSERT INTO auth.users (id, subject) VALUES ($1, $2) ON CONFLICT (subject) DO NOTHING", uuid.New(), subject)
+	if err != nil {
+		return nil, err
+	}
+	return GetUserBySubject(ctx, subject)
+}
@@ -0,0 +1,254 @@
+// Package gc reclaims storage held by uploads that were abandoned before they
+// were finalized.
+package gc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/metrics"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// LeaseName is the lease held by the replica running the collector.
+const LeaseName = "upload_gc"
+
+type Config struct {
+	Enabled bool
+	// Interval is the time between collections.
+	Interval time.Duration
+	// TTL is how long an upload must go without activity before it counts as
+	// abandoned. tus uploads are abandoned once they expire instead.
+	TTL time.Duration
+	// DryRun logs what would be deleted without deleting anything.
+	DryRun bool
+	// BatchSize is the most uploads collected in one run.
+	BatchSize int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Enabled:   true,
+		Interval:  15 * time.Minute,
+		TTL:       48 * time.Hour,
+		DryRun:    false,
+		BatchSize: 100,
+	}
+}
+
+// ConfigFromEnv reads GC_ENABLED, GC_INTERVAL, GC_UPLOAD_TTL, GC_DRY_RUN and
+// GC_BATCH_SIZE, falling back to DefaultConfig for unset variables.
+func ConfigFromEnv(getenv func(string) string) (Config, error) {
+	config := DefaultConfig()
+	var err error
+	if value := getenv("GC_ENABLED"); value != "" {
+		if config.Enabled, err = strconv.ParseBool(value); err != nil {
+			return config, fmt.Errorf("invalid GC_ENABLED: %w", err)
+		}
+	}
+	if value := getenv("GC_INTERVAL"); value != "" {
+		if config.Interval, err = time.ParseDuration(value); err != nil || config.Interval <= 0 {
+			return config, fmt.Errorf("invalid GC_INTERVAL: %q", value)
+		}
+	}
+	if value := getenv("GC_UPLOAD_TTL"); value != "" {
+		if config.TTL, err = time.ParseDuration(value); err != nil || config.TTL <= 0 {
+			return config, fmt.Errorf("invalid GC_UPLOAD_TTL: %q", value)
+		}
+	}
+	if value := getenv("GC_DRY_RUN"); value != "" {
+		if config.DryRun, err = strconv.ParseBool(value); err != nil {
+			return config, fmt.Errorf("invalid GC_DRY_RUN: %w", err)
+		}
+	}
+	if value := getenv("GC_BATCH_SIZE"); value != "" {
+		if config.BatchSize, err = strconv.Atoi(value); err != nil || config.BatchSize <= 0 {
+			return config, fmt.Errorf("invalid GC_BATCH_SIZE: %q", value)
+		}
+	}
+	return config, nil
+}
+
+// Collector periodically deletes the objects and rows of uploads that were
+// not finalized within the TTL. Replicas compete for a lease in the database
+// so that only one of them collects at a time.
+type Collector struct {
+	pool   *pgxpool.Pool
+	bucket *storage.Bucket
+	config Config
+	holder string
+	// dryRun is the dry_run label of the metrics the collector updates
+	dryRun string
+}
+
+func New(pool *pgxpool.Pool, bucket *storage.Bucket, config Config) *Collector {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	hostname, _ := os.Hostname()
+	return &Collector{
+		pool:   pool,
+		bucket: bucket,
+		config: config,
+		holder: fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(suffix)),
+		dryRun: strconv.FormatBool(config.DryRun),
+	}
+}
+
+// Run collects every interval until ctx is done. It does nothing if the
+// collector is disabled.
+func (c *Collector) Run(ctx context.Context) {
+	if !c.config.Enabled {
+		return
+	}
+	slog.Info("Starting upload garbage collector", "interval", c.config.Interval, "ttl", c.config.TTL, "dry_run", c.config.DryRun)
+	ticker := time.NewTicker(c.config.Interval)
+	defer ticker.Stop()
+	for {
+		if err := c.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Upload garbage collection failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			c.release()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce collects one batch of abandoned uploads if this replica holds the
+// lease.
+func (c *Collector) RunOnce(ctx context.Context) error {
+	ctx = db.WithConnPool(ctx, c.pool)
+	// Hold the lease until well after the next run would start, so a replica
+	// keeps it for as long as it is alive
+	acquired, err := db.AcquireLease(ctx, LeaseName, c.holder, 2*c.config.Interval)
+	if err != nil {
+		metrics.GCErrors.WithLabelValues(c.dryRun).Inc()
+		return fmt.Errorf("acquiring lease: %w", err)
+	}
+	if !acquired {
+		return nil
+	}
+
+	start := time.Now()
+	cutoff := start.Add(-c.config.TTL)
+	uploadIDs, err := db.ListStaleUploads(ctx, cutoff, c.config.BatchSize)
+	if err != nil {
+		metrics.GCErrors.WithLabelValues(c.dryRun).Inc()
+		return fmt.Errorf("listing stale uploads: %w", err)
+	}
+
+	var uploads, objects, bytes, failures int64
+	for _, id := range uploadIDs {
+		deletedObjects, deletedBytes, err := c.collect(ctx, id, cutoff)
+		objects += deletedObjects
+		bytes += deletedBytes
+		if errors.Is(err, errNotStale) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			failures++
+			slog.Error("Failed to collect upload", "upload_id", id, "error", err)
+			continue
+		}
+		uploads++
+	}
+
+	metrics.GCRuns.WithLabelValues(c.dryRun).Inc()
+	metrics.GCUploadsDeleted.WithLabelValues(c.dryRun).Add(float64(uploads))
+	metrics.GCObjectsDeleted.WithLabelValues(c.dryRun).Add(float64(objects))
+	metrics.GCBytesReclaimed.WithLabelValues(c.dryRun).Add(float64(bytes))
+	metrics.GCErrors.WithLabelValues(c.dryRun).Add(float64(failures))
+	metrics.GCLastRunTimestamp.WithLabelValues(c.dryRun).Set(float64(start.Unix()))
+	metrics.GCLastRunDuration.WithLabelValues(c.dryRun).Set(time.Since(start).Seconds())
+	slog.Info("Upload garbage collection finished",
+		"dry_run", c.config.DryRun,
+		"uploads", uploads,
+		"objects", objects,
+		"bytes", bytes,
+		"errors", failures,
+		"duration", time.Since(start),
+	)
+	return ctx.Err()
+}
+
+// errNotStale is returned by collect for uploads that are no longer stale.
+var errNotStale = errors.New("upload is no longer stale")
+
+// collect claims a stale upload, then deletes its objects and then its rows.
+// The rows are kept if any object could not be deleted, so that the next run
+// retries. Uploads that have seen activity since they were listed, including
+// uploads being finalized, are not claimed and left alone.
+func (c *Collector) collect(ctx context.Context, uploadID uuid.UUID, cutoff time.Time) (objects int64, bytes int64, err error) {
+	if !c.config.DryRun {
+		claimed, err := db.ClaimStaleUpload(ctx, uploadID, cutoff)
+		if err != nil {
+			return 0, 0, fmt.Errorf("claiming upload: %w", err)
+		}
+		if !claimed {
+			return 0, 0, errNotStale
+		}
+	}
+
+	partKeys, err := db.GetUploadPartObjectKeys(ctx, uploadID)
+	if err != nil {
+		return 0, 0, err
+	}
+	segmentKeys, err := db.GetTusSegmentObjectKeys(ctx, uploadID)
+	if err != nil {
+		return 0, 0, err
+	}
+	// The final object exists if finalizing failed after composing the parts
+	keys := append(append(partKeys, segmentKeys...), db.FinalObjectKey(uploadID))
+
+	for _, key := range keys {
+		attrs, err := c.bucket.Attrs(ctx, key)
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			continue
+		} else if err != nil {
+			return objects, bytes, fmt.Errorf("reading attributes of %s: %w", key, err)
+		}
+		if c.config.DryRun {
+			slog.Info("Would delete object", "upload_id", uploadID, "object_key", key, "size", attrs.Size)
+		} else if err := c.bucket.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+			return objects, bytes, fmt.Errorf("deleting %s: %w", key, err)
+		}
+		objects++
+		bytes += attrs.Size
+	}
+
+	if c.config.DryRun {
+		slog.Info("Would delete upload", "upload_id", uploadID)
+		return objects, bytes, nil
+	}
+	err = db.PurgeUpload(ctx, uploadID)
+	var notFound db.ErrUploadNotFound
+	if errors.As(err, &notFound) {
+		return objects, bytes, nil
+	}
+	return objects, bytes, err
+}
+
+// release gives up the lease so another replica can take over without waiting
+// for it to expire.
+func (c *Collector) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := db.ReleaseLease(db.WithConnPool(ctx, c.pool), LeaseName, c.holder); err != nil {
+		slog.Error("Failed to release garbage collector lease", "error", err)
+	}
+}
@@ -0,0 +1,55 @@
+package gc
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("GC_ENABLED", "false")
+	t.Setenv("GC_INTERVAL", "1m")
+	t.Setenv("GC_UPLOAD_TTL", "2h")
+	t.Setenv("GC_DRY_RUN", "true")
+	t.Setenv("GC_BATCH_SIZE", "10")
+
+	config, err := ConfigFromEnv(os.Getenv)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := Config{Enabled: false, Interval: time.Minute, TTL: 2 * time.Hour, DryRun: true, BatchSize: 10}
+	if config != expected {
+		t.Fatalf("Expected %+v, got %+v", expected, config)
+	}
+}
+
+func TestConfigFromEnv_Defaults(t *testing.T) {
+	for _, name := range []string{"GC_ENABLED", "GC_INTERVAL", "GC_UPLOAD_TTL", "GC_DRY_RUN", "GC_BATCH_SIZE"} {
+		t.Setenv(name, "")
+	}
+	config, err := ConfigFromEnv(os.Getenv)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if config != DefaultConfig() {
+		t.Fatalf("Expected %+v, got %+v", DefaultConfig(), config)
+	}
+}
+
+func TestConfigFromEnv_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"GC_ENABLED":    "maybe",
+		"GC_INTERVAL":   "0s",
+		"GC_UPLOAD_TTL": "soon",
+		"GC_DRY_RUN":    "2",
+		"GC_BATCH_SIZE": "-1",
+	}
+	for name, value := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			if _, err := ConfigFromEnv(os.Getenv); err == nil {
+				t.Fatalf("Expected error for %s=%q, got nil", name, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestChecker_Report(t *testing.T) {
+	t.Parallel()
+	checker := NewChecker(time.Second, time.Minute,
+		Check{Name: "up", Check: func(ctx context.Context) error { return nil }},
+		Check{Name: "down", Check: func(ctx context.Context) error { return errors.New("connection refused") }},
+	)
+	report := checker.Report(context.Background())
+	if report.OK() {
+		t.Fatalf("Expected report to be unavailable, got %+v", report)
+	}
+	if report.Checks["up"].Status != StatusOK {
+		t.Fatalf("Expected up to be ok, got %+v", report.Checks["up"])
+	}
+	if down := report.Checks["down"]; down.Status != StatusUnavailable || down.Error != "connection refused" {
+		t.Fatalf("Expected down to be unavailable with its error, got %+v", down)
+	}
+}
+
+func TestChecker_Timeout(t *testing.T) {
+	t.Parallel()
+	checker := NewChecker(10*time.Millisecond, time.Minute, Check{Name: "slow", Check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	if report := checker.Report(context.Background()); report.OK() {
+		t.Fatalf("Expected slow check to time out, got %+v", report)
+	}
+}
+
+func TestChecker_Cache(t *testing.T) {
+	t.Parallel()
+	var runs atomic.Int32
+	checker := NewChecker(time.Second, 5*time.Second, Check{Name: "counted", Check: func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	}})
+	now := time.Unix(0, 0)
+	checker.now = func() time.Time { return now }
+
+	checker.Report(context.Background())
+	checker.Report(context.Background())
+	if runs.Load() != 1 {
+		t.Fatalf("Expected cached report to be reused, got %d runs", runs.Load())
+	}
+	now = now.Add(5 * time.Second)
+	checker.Report(context.Background())
+	if runs.Load() != 2 {
+		t.Fatalf("Expected expired report to be refreshed, got %d runs", runs.Load())
+	}
+}
@@ -0,0 +1,38 @@
+// Package logging carries a request-scoped logger in the context, so that
+// everything logged while serving a request can be correlated with it.
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+type ctxKey string
+
+const (
+	loggerKey    ctxKey = "logger"
+	requestIDKey ctxKey = "request_id"
+)
+
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// FromContext returns the logger of the request ctx belongs to, or the
+// default logger outside of a request.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFrom returns the ID of the request ctx belongs to, if any.
+func RequestIDFrom(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDKey).(string)
+	return requestID, ok
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func poolDesc(name string, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+}
+
+var (
+	poolAcquiredConns     = poolDesc("acquired_conns", "Connections currently in use.")
+	poolIdleConns         = poolDesc("idle_conns", "Connections currently idle.")
+	poolConstructingConns = poolDesc("constructing_conns", "Connections currently being opened.")
+	poolTotalConns        = poolDesc("total_conns", "Connections currently open or being opened.")
+	poolMaxConns          = poolDesc("max_conns", "Largest number of connections the pool opens.")
+	poolAcquires          = poolDesc("acquires_total", "Connections acquired from the pool.")
+	poolEmptyAcquires     = poolDesc("empty_acquires_total", "Acquires that waited for a connection because none was idle.")
+	poolCanceledAcquires  = poolDesc("canceled_acquires_total", "Acquires cancelled by their context.")
+	poolAcquireSeconds    = poolDesc("acquire_duration_seconds_total", "Time spent waiting to acquire connections.")
+)
+
+// PoolCollector exports the statistics of a connection pool.
+type PoolCollector struct {
+	pool *pgxpool.Pool
+}
+
+func NewPoolCollector(pool *pgxpool.Pool) *PoolCollector {
+	return &PoolCollector{pool: pool}
+}
+
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value)
+	}
+	counter := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value)
+	}
+	gauge(poolAcquiredConns, float64(stat.AcquiredConns()))
+	gauge(poolIdleConns, float64(stat.IdleConns()))
+	gauge(poolConstructingConns, float64(stat.ConstructingConns()))
+	gauge(poolTotalConns, float64(stat.TotalConns()))
+	gauge(poolMaxConns, float64(stat.MaxConns()))
+	counter(poolAcquires, float64(stat.AcquireCount()))
+	counter(poolEmptyAcquires, float64(stat.EmptyAcquireCount()))
+	counter(poolCanceledAcquires, float64(stat.CanceledAcquireCount()))
+	counter(poolAcquireSeconds, stat.AcquireDuration().Seconds())
+}
+
+var uploadsDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "uploads"), "Uploads, by status.", []string{"status"}, nil)
+
+// UploadsCollector exports the number of uploads in each status, counted in
+// the database on every scrape.
+type UploadsCollector struct {
+	pool *pgxpool.Pool
+}
+
+func NewUploadsCollector(pool *pgxpool.Pool) *UploadsCollector {
+	return &UploadsCollector{pool: pool}
+}
+
+func (c *UploadsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- uploadsDesc
+}
+
+func (c *UploadsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	counts, err := db.CountUploadsByStatus(db.WithConnPool(ctx, c.pool))
+	if err != nil {
+		slog.Error("Failed to count uploads for metrics", "error", err)
+		ch <- prometheus.NewInvalidMetric(uploadsDesc, err)
+		return
+	}
+	statuses := []db.UploadStatus{db.UploadStatusPending, db.UploadStatusInProgress, db.UploadStatusCompleted, db.UploadStatusFailed}
+	for _, status := range statuses {
+		ch <- prometheus.MustNewConstMetric(uploadsDesc, prometheus.GaugeValue, float64(counts[status]), string(status))
+	}
+}
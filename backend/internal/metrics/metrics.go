@@ -0,0 +1,146 @@
+// Package metrics defines the Prometheus metrics of the server, so that
+// slowness can be traced to the server itself, CockroachDB or the bucket.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "transfer"
+
+// durationBuckets span 5ms to about 40s, as requests and bucket operations
+// on large objects take far longer than the default buckets allow for.
+var durationBuckets = prometheus.ExponentialBuckets(0.005, 2, 14)
+
+// Registry holds every metric of the server. The metrics below are registered
+// with it, and collectors that need the database are registered by main.
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests served, by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to serve HTTP requests, by method, route and status.",
+		Buckets:   durationBuckets,
+	}, []string{"method", "route", "status"})
+
+	TransferredBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transferred_bytes_total",
+		Help:      "Bytes of file content uploaded and downloaded, by direction.",
+	}, []string{"direction"})
+
+	PartUploadFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "part_upload_failures_total",
+		Help:      "Parts marked as failed after an upload error or failed verification.",
+	})
+
+	// Streamed operations (range_read, download_to and upload_from) are timed
+	// through the whole transfer, so they include time spent waiting on the
+	// other end of the stream.
+	StorageOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_duration_seconds",
+		Help:      "Time taken by object storage operations, by operation and result. Streamed operations are timed through the whole transfer.",
+		Buckets:   durationBuckets,
+	}, []string{"operation", "result"})
+
+	// The metrics of the garbage collector count what would have been
+	// reclaimed when it runs in dry run mode, which dry_run tells apart.
+	GCRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gc_runs_total",
+		Help:      "Garbage collection runs completed.",
+	}, []string{"dry_run"})
+
+	GCUploadsDeleted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gc_uploads_deleted_total",
+		Help:      "Abandoned uploads deleted by the garbage collector.",
+	}, []string{"dry_run"})
+
+	GCObjectsDeleted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gc_objects_deleted_total",
+		Help:      "Objects of abandoned uploads deleted by the garbage collector.",
+	}, []string{"dry_run"})
+
+	GCBytesReclaimed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gc_bytes_reclaimed_total",
+		Help:      "Bytes of object storage reclaimed by the garbage collector.",
+	}, []string{"dry_run"})
+
+	GCErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gc_errors_total",
+		Help:      "Garbage collection runs and uploads that failed.",
+	}, []string{"dry_run"})
+
+	GCLastRunTimestamp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "gc_last_run_timestamp_seconds",
+		Help:      "Unix time the last garbage collection run started.",
+	}, []string{"dry_run"})
+
+	GCLastRunDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "gc_last_run_duration_seconds",
+		Help:      "Time taken by the last garbage collection run.",
+	}, []string{"dry_run"})
+)
+
+// The directions of TransferredBytes.
+const (
+	DirectionUpload   = "upload"
+	DirectionDownload = "download"
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		TransferredBytes,
+		PartUploadFailures,
+		StorageOperationDuration,
+		GCRuns,
+		GCUploadsDeleted,
+		GCObjectsDeleted,
+		GCBytesReclaimed,
+		GCErrors,
+		GCLastRunTimestamp,
+		GCLastRunDuration,
+	)
+}
+
+// Handler serves the metrics in Registry.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveStorageOperation records an object storage operation that started
+// at start and ended with err. A missing object is recorded apart from other
+// errors, since it is often expected.
+func ObserveStorageOperation(operation string, start time.Time, err error, notFound bool) {
+	result := "ok"
+	if notFound {
+		result = "not_found"
+	} else if err != nil {
+		result = "error"
+	}
+	StorageOperationDuration.WithLabelValues(operation, result).Observe(time.Since(start).Seconds())
+}
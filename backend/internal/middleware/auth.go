@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/auth"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/logging"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/problem"
+)
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	problem.Write(w, status, message)
+}
+
+// Authenticate puts the caller identified by authenticator into the request
+// context. Requests without credentials are passed on unauthenticated, for
+// RequireScope to reject where needed. Requests with invalid credentials are
+// rejected.
+func Authenticate(authenticator *auth.Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := authenticator.Authenticate(r)
+			switch {
+			case errors.Is(err, auth.ErrNoCredentials):
+				next.ServeHTTP(w, r)
+			case errors.Is(err, auth.ErrInvalidCredentials):
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				writeError(w, http.StatusUnauthorized, "invalid credentials")
+			case err != nil:
+				logging.FromContext(r.Context()).Error("Authentication failed", "method", r.Method, "path", r.URL.Path, "error", err)
+				writeError(w, http.StatusInternalServerError, "internal server error")
+			default:
+				ctx := auth.WithPrincipal(r.Context(), principal)
+				ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("subject", principal.Subject))
+				next.ServeHTTP(w, r.WithContext(ctx))
+			}
+		})
+	}
+}
+
+// RequireScope only lets callers authenticated with scope through to next.
+func RequireScope(scope string, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFrom(r.Context())
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		if !principal.HasScope(scope) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, scope))
+			writeError(w, http.StatusForbidden, "missing scope "+scope)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
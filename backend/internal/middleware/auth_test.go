@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/auth"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestRequireScope(t *testing.T) {
+	t.Parallel()
+	authenticator := auth.NewAuthenticator(auth.Config{HS256Secrets: [][]byte{[]byte("secret")}})
+	authenticator.LookupUser = func(ctx context.Context, subject string) (*db.User, error) {
+		return &db.User{ID: uuid.New(), Subject: subject}, nil
+	}
+	handler := Authenticate(authenticator)(RequireScope(auth.ScopeUploadWrite, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	token := func(scope string) string {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"sub":   "user-1",
+			"exp":   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			"scope": scope,
+		}).SignedString([]byte("secret"))
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		return signed
+	}
+
+	cases := []struct {
+		authorization string
+		status        int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer garbage", http.StatusUnauthorized},
+		{"Bearer " + token(auth.ScopeUploadRead), http.StatusForbidden},
+		{"Bearer " + token(auth.ScopeUploadRead+" "+auth.ScopeUploadWrite), http.StatusNoContent},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodPost, "/uploads", nil)
+		if c.authorization != "" {
+			r.Header.Set("Authorization", c.authorization)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != c.status {
+			t.Fatalf("Expected status %d for %q, got %d", c.status, c.authorization, w.Code)
+		}
+	}
+}
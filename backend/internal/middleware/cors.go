@@ -1,29 +1,161 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
 	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
-var ALLOWED_ORIGINS = []*regexp.Regexp{
-	regexp.MustCompile("http://localhost:.*"),
+type CORSConfig struct {
+	// AllowedOrigins are the origins allowed to call the API. A * matches any
+	// run of characters, so "*" allows every origin and
+	// "https://*.example.com" every subdomain.
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response.
+	MaxAge time.Duration
 }
 
-func CORSMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "OPTIONS" {
-			for _, origin := range ALLOWED_ORIGINS {
-				if origin.MatchString(r.Header.Get("Origin")) {
-					w.Header().Set("Access-Control-Allow-Origin", "*")
-					w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-					w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-					w.WriteHeader(http.StatusOK)
+func DefaultCORSConfig() CORSConfig {
+	return CORSConfig{
+		AllowedOrigins: []string{"http://localhost:*"},
+		AllowedMethods: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{
+			"Content-Type", "Authorization", "X-API-Key", "X-Request-ID", "Range",
+			"traceparent", "tracestate",
+			"Content-Digest", "Tus-Resumable", "Upload-Length", "Upload-Offset",
+			"Upload-Metadata", "Upload-Checksum", "Upload-Defer-Length",
+		},
+		ExposedHeaders: []string{
+			"ETag", "Content-Range", "Content-Disposition", "Location", "X-Request-ID",
+			"Upload-Offset", "Upload-Length", "Upload-Expires", "Upload-Metadata",
+			"Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size", "Tus-Checksum-Algorithm",
+			"Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset",
+		},
+		AllowCredentials: false,
+		MaxAge:           time.Hour,
+	}
+}
+
+// CORSConfigFromEnv reads CORS_ALLOWED_ORIGINS, CORS_ALLOWED_METHODS,
+// CORS_ALLOWED_HEADERS and CORS_EXPOSED_HEADERS, each a comma separated list,
+// CORS_ALLOW_CREDENTIALS and CORS_MAX_AGE, falling back to DefaultCORSConfig
+// for unset variables.
+func CORSConfigFromEnv(getenv func(string) string) (CORSConfig, error) {
+	config := DefaultCORSConfig()
+	lists := []struct {
+		name string
+		list *[]string
+	}{
+		{"CORS_ALLOWED_ORIGINS", &config.AllowedOrigins},
+		{"CORS_ALLOWED_METHODS", &config.AllowedMethods},
+		{"CORS_ALLOWED_HEADERS", &config.AllowedHeaders},
+		{"CORS_EXPOSED_HEADERS", &config.ExposedHeaders},
+	}
+	for _, l := range lists {
+		if value := getenv(l.name); value != "" {
+			*l.list = splitList(value)
+		}
+	}
+	var err error
+	if value := getenv("CORS_ALLOW_CREDENTIALS"); value != "" {
+		if config.AllowCredentials, err = strconv.ParseBool(value); err != nil {
+			return config, fmt.Errorf("invalid CORS_ALLOW_CREDENTIALS: %w", err)
+		}
+	}
+	if value := getenv("CORS_MAX_AGE"); value != "" {
+		if config.MaxAge, err = time.ParseDuration(value); err != nil || config.MaxAge < 0 {
+			return config, fmt.Errorf("invalid CORS_MAX_AGE: %q", value)
+		}
+	}
+	return config, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// originPattern compiles an allowed origin, in which * matches any run of
+// characters.
+func originPattern(origin string) *regexp.Regexp {
+	parts := strings.Split(origin, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
+
+// CORS answers preflight requests from allowed origins and lets them read
+// responses. The matching origin is echoed rather than *, so that credentials
+// can be allowed. Responses vary by Origin whether or not it is allowed, so
+// that caches do not serve one origin's response to another. Other OPTIONS
+// requests, such as tus capability discovery, go to the handler.
+func CORS(config CORSConfig) func(http.Handler) http.Handler {
+	origins := make([]*regexp.Regexp, len(config.AllowedOrigins))
+	for i, origin := range config.AllowedOrigins {
+		origins[i] = originPattern(origin)
+	}
+	allowed := func(origin string) bool {
+		return slices.ContainsFunc(origins, func(pattern *regexp.Regexp) bool {
+			return pattern.MatchString(origin)
+		})
+	}
+	allowMethods := strings.Join(config.AllowedMethods, ", ")
+	allowHeaders := strings.Join(config.AllowedHeaders, ", ")
+	exposeHeaders := strings.Join(config.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(config.MaxAge.Seconds()))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			header.Add("Vary", "Origin")
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if preflight {
+				header.Add("Vary", "Access-Control-Request-Method")
+				header.Add("Vary", "Access-Control-Request-Headers")
+			}
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !allowed(origin) {
+				if preflight {
+					w.WriteHeader(http.StatusForbidden)
 					return
 				}
+				next.ServeHTTP(w, r)
+				return
 			}
-			w.WriteHeader(http.StatusForbidden)
-			return
-		}
-		next.ServeHTTP(w, r)
-	})
+
+			header.Set("Access-Control-Allow-Origin", origin)
+			if config.AllowCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if preflight {
+				header.Set("Access-Control-Allow-Methods", allowMethods)
+				header.Set("Access-Control-Allow-Headers", allowHeaders)
+				header.Set("Access-Control-Max-Age", maxAge)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			if exposeHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
 }
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	t.Parallel()
+	config := DefaultCORSConfig()
+	config.AllowedOrigins = []string{"https://*.example.com", "http://localhost:*"}
+	config.AllowCredentials = true
+	handler := CORS(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	cases := []struct {
+		method        string
+		origin        string
+		preflight     bool
+		status        int
+		allowedOrigin string
+	}{
+		{http.MethodGet, "https://app.example.com", false, http.StatusTeapot, "https://app.example.com"},
+		{http.MethodGet, "http://localhost:5173", false, http.StatusTeapot, "http://localhost:5173"},
+		{http.MethodGet, "https://example.com.evil.com", false, http.StatusTeapot, ""},
+		{http.MethodGet, "", false, http.StatusTeapot, ""},
+		{http.MethodOptions, "https://app.example.com", true, http.StatusNoContent, "https://app.example.com"},
+		{http.MethodOptions, "https://evil.com", true, http.StatusForbidden, ""},
+		{http.MethodOptions, "https://app.example.com", false, http.StatusTeapot, "https://app.example.com"},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(c.method, "/uploads", nil)
+		if c.origin != "" {
+			r.Header.Set("Origin", c.origin)
+		}
+		if c.preflight {
+			r.Header.Set("Access-Control-Request-Method", http.MethodPut)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != c.status {
+			t.Fatalf("Expected status %d for %s from %q, got %d", c.status, c.method, c.origin, w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != c.allowedOrigin {
+			t.Fatalf("Expected Access-Control-Allow-Origin %q for %q, got %q", c.allowedOrigin, c.origin, got)
+		}
+		if !slices.Contains(w.Header().Values("Vary"), "Origin") {
+			t.Fatalf("Expected Vary: Origin for %q, got %v", c.origin, w.Header().Values("Vary"))
+		}
+		if c.allowedOrigin != "" && w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+			t.Fatalf("Expected Access-Control-Allow-Credentials for %q", c.origin)
+		}
+		if c.allowedOrigin != "" && !c.preflight && w.Header().Get("Access-Control-Expose-Headers") == "" {
+			t.Fatalf("Expected Access-Control-Expose-Headers for %q", c.origin)
+		}
+		if c.preflight && c.allowedOrigin != "" && w.Header().Get("Access-Control-Max-Age") != "3600" {
+			t.Fatalf("Expected Access-Control-Max-Age 3600, got %q", w.Header().Get("Access-Control-Max-Age"))
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ConnPool makes the pool available to db functions called from handlers. A
+// nil pool is not added, so that db functions fail rather than panic.
+func ConnPool(pool *pgxpool.Pool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if pool == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(db.WithConnPool(r.Context(), pool)))
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/logging"
+)
+
+func TestLogger_RequestID(t *testing.T) {
+	t.Parallel()
+	var seen string
+	handler := Logger(slog.Default())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = logging.RequestIDFrom(r.Context())
+	}))
+
+	cases := []struct {
+		header   string
+		expected string
+	}{
+		{"abc-123", "abc-123"},
+		{"", ""},
+		{"has space", ""},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if c.header != "" {
+			r.Header.Set("X-Request-ID", c.header)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		requestID := w.Header().Get("X-Request-ID")
+		if requestID == "" || requestID != seen {
+			t.Fatalf("Expected response and context request IDs to match, got %q and %q", requestID, seen)
+		}
+		if c.expected != "" && requestID != c.expected {
+			t.Fatalf("Expected request ID %q, got %q", c.expected, requestID)
+		}
+		if c.expected == "" && requestID == c.header {
+			t.Fatalf("Expected request ID %q to be replaced", c.header)
+		}
+	}
+}
+
+func TestLogger_AccessLog(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	handler := Logger(slog.New(slog.NewJSONHandler(&out, nil)))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	}))
+	r := httptest.NewRequest(http.MethodPost, "/uploads", nil)
+	r.Header.Set("X-Request-ID", "req-1")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	var line map[string]any
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatalf("Expected one JSON log line, got %q", out.String())
+	}
+	if line["request_id"] != "req-1" || line["status"] != float64(http.StatusCreated) || line["bytes"] != float64(5) {
+		t.Fatalf("Expected request_id req-1, status 201 and bytes 5, got %v", line)
+	}
+}
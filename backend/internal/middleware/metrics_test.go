@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics-test/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	handler := Metrics(mux)(mux)
+
+	for _, path := range []string{"/metrics-test/1", "/metrics-test/2"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics-test-missing", nil))
+
+	if count := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("GET", "/metrics-test/{id}", "202")); count != 2 {
+		t.Fatalf("Expected 2 requests for the route, got %v", count)
+	}
+	if count := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("GET", unmatchedRoute, "404")); count < 1 {
+		t.Fatalf("Expected unmatched request to be counted, got %v", count)
+	}
+}
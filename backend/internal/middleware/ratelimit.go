@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/auth"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/logging"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/ratelimit"
+)
+
+// RateLimiter limits callers by their IP and, if they authenticated, by their
+// API key or their user. Requests are allowed through if the store fails, so
+// that an outage of the store does not take the API down with it.
+type RateLimiter struct {
+	store  ratelimit.Store
+	config ratelimit.Config
+}
+
+func NewRateLimiter(store ratelimit.Store, config ratelimit.Config) *RateLimiter {
+	return &RateLimiter{store: store, config: config}
+}
+
+// Requests limits the rate of requests to the API. It must run after
+// Authenticate.
+func (l *RateLimiter) Requests(next http.Handler) http.Handler {
+	if !l.config.Enabled {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l.take(w, r, "requests", l.config.Requests, 1, false) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// Creates limits the rate at which uploads are created.
+func (l *RateLimiter) Creates(next http.HandlerFunc) http.HandlerFunc {
+	if !l.config.Enabled {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if l.take(w, r, "creates", l.config.Creates, 1, false) {
+			next(w, r)
+		}
+	}
+}
+
+// Bandwidth limits the bytes read from request bodies and written to
+// responses. The size of a transfer is not known up front, so a request is
+// let through while the caller has any budget left and charged for what it
+// transferred once it is done, which may leave the caller in debt.
+func (l *RateLimiter) Bandwidth(next http.HandlerFunc) http.HandlerFunc {
+	if !l.config.Enabled {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !l.take(w, r, "bytes", l.config.Bytes, 0, false) {
+			return
+		}
+		body := &countingReader{ReadCloser: r.Body}
+		r.Body = body
+		recorder := &responseRecorder{ResponseWriter: w}
+		next(recorder, r)
+
+		// Charge even if the request was cancelled, as the bytes were still sent
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+		defer cancel()
+		for _, key := range l.keys(r, "bytes") {
+			_, err := l.store.Take(ctx, key, l.config.Bytes, float64(body.n+recorder.bytes), true)
+			if err != nil {
+				logging.FromContext(ctx).Error("Failed to charge rate limit", "bucket", "bytes", "error", err)
+			}
+		}
+	}
+}
+
+// take takes cost tokens from each of the caller's buckets and sets the
+// RateLimit headers from the one with the fewest left. If a bucket does not
+// hold enough, it responds with 429 and returns false.
+func (l *RateLimiter) take(w http.ResponseWriter, r *http.Request, bucket string, limit ratelimit.Limit, cost float64, force bool) bool {
+	var tightest *ratelimit.Result
+	for _, key := range l.keys(r, bucket) {
+		result, err := l.store.Take(r.Context(), key, limit, cost, force)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Failed to check rate limit", "bucket", bucket, "error", err)
+			continue
+		}
+		if tightest == nil || !result.Allowed || result.Remaining < tightest.Remaining {
+			tightest = &result
+		}
+		if !result.Allowed {
+			break
+		}
+	}
+	if tightest == nil {
+		return true
+	}
+	w.Header().Set("RateLimit-Limit", strconv.FormatFloat(limit.Burst, 'f', 0, 64))
+	w.Header().Set("RateLimit-Remaining", strconv.FormatFloat(max(math.Floor(tightest.Remaining), 0), 'f', 0, 64))
+	w.Header().Set("RateLimit-Reset", strconv.FormatInt(ceilSeconds(tightest.Reset), 10))
+	if !tightest.Allowed {
+		w.Header().Set("Retry-After", strconv.FormatInt(ceilSeconds(tightest.RetryAfter), 10))
+		writeError(w, http.StatusTooManyRequests, fmt.Sprintf("rate limit exceeded, retry in %s", tightest.RetryAfter.Round(time.Second)))
+		return false
+	}
+	return true
+}
+
+// keys returns the caller's buckets: one for their IP and, if they
+// authenticated, one for their API key or user. Requests count against both,
+// so neither sharing a key across IPs nor rotating keys on one IP gets around
+// the limit.
+func (l *RateLimiter) keys(r *http.Request, bucket string) []string {
+	keys := []string{bucket + ":ip:" + l.clientIP(r)}
+	if principal, ok := auth.PrincipalFrom(r.Context()); ok {
+		if principal.Method == auth.MethodAPIKey {
+			return append(keys, bucket+":key:"+principal.KeyID.String())
+		}
+		return append(keys, bucket+":user:"+principal.UserID.String())
+	}
+	return keys
+}
+
+// clientIP returns the IP the request came from. Behind a trusted proxy this
+// is the last address in X-Forwarded-For, which the proxy appended, as the
+// earlier ones can be set by the client.
+func (l *RateLimiter) clientIP(r *http.Request) string {
+	if l.config.TrustProxy {
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			addresses := strings.Split(forwarded[len(forwarded)-1], ",")
+			if ip := strings.TrimSpace(addresses[len(addresses)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
+
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
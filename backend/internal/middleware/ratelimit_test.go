@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/auth"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/ratelimit"
+	"github.com/google/uuid"
+)
+
+func TestRateLimiter_Requests(t *testing.T) {
+	t.Parallel()
+	config := ratelimit.DefaultConfig()
+	config.Requests = ratelimit.Every(2, time.Minute)
+	limiter := NewRateLimiter(ratelimit.NewMemoryStore(), config)
+	handler := limiter.Requests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	request := func(remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/uploads/1", nil)
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+	for i := 0; i < 2; i++ {
+		if w := request("10.0.0.1:1234"); w.Code != http.StatusNoContent {
+			t.Fatalf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+		}
+	}
+	w := request("10.0.0.1:5678")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if w.Header().Get("Retry-After") != "30" {
+		t.Fatalf("Expected Retry-After 30, got %q", w.Header().Get("Retry-After"))
+	}
+	if w.Header().Get("RateLimit-Limit") != "2" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("Expected RateLimit-Limit 2 and RateLimit-Remaining 0, got %q and %q", w.Header().Get("RateLimit-Limit"), w.Header().Get("RateLimit-Remaining"))
+	}
+	if w := request("10.0.0.2:1234"); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected other IP to be allowed, got %d", w.Code)
+	}
+}
+
+func TestRateLimiter_KeyAndIP(t *testing.T) {
+	t.Parallel()
+	config := ratelimit.DefaultConfig()
+	config.Requests = ratelimit.Every(2, time.Minute)
+	limiter := NewRateLimiter(ratelimit.NewMemoryStore(), config)
+	handler := limiter.Requests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	request := func(remoteAddr string, keyID uuid.UUID) int {
+		r := httptest.NewRequest(http.MethodGet, "/uploads/1", nil)
+		r.RemoteAddr = remoteAddr
+		r = r.WithContext(auth.WithPrincipal(r.Context(), &auth.Principal{Method: auth.MethodAPIKey, KeyID: keyID}))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+	key := uuid.New()
+	if status := request("10.0.0.1:1234", key); status != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d", http.StatusNoContent, status)
+	}
+	if status := request("10.0.0.2:1234", key); status != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d", http.StatusNoContent, status)
+	}
+	// The key is used up, whichever IP it is used from
+	if status := request("10.0.0.3:1234", key); status != http.StatusTooManyRequests {
+		t.Fatalf("Expected status %d for the key, got %d", http.StatusTooManyRequests, status)
+	}
+	// The IP is used up, whichever key it uses
+	if status := request("10.0.0.1:1234", uuid.New()); status != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d", http.StatusNoContent, status)
+	}
+	if status := request("10.0.0.1:1234", uuid.New()); status != http.StatusTooManyRequests {
+		t.Fatalf("Expected status %d for the IP, got %d", http.StatusTooManyRequests, status)
+	}
+}
+
+func TestRateLimiter_Bandwidth(t *testing.T) {
+	t.Parallel()
+	config := ratelimit.DefaultConfig()
+	config.Bytes = ratelimit.Every(10, time.Minute)
+	limiter := NewRateLimiter(ratelimit.NewMemoryStore(), config)
+	handler := limiter.Bandwidth(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Write([]byte("hello"))
+	})
+
+	request := func() int {
+		r := httptest.NewRequest(http.MethodPut, "/uploads/1/parts/1", strings.NewReader("0123456789"))
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w.Code
+	}
+	if status := request(); status != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, status)
+	}
+	// The first request read 10 bytes and wrote 5, leaving the caller in debt
+	if status := request(); status != http.StatusTooManyRequests {
+		t.Fatalf("Expected status %d, got %d", http.StatusTooManyRequests, status)
+	}
+}
+
+func TestRateLimiter_TrustProxy(t *testing.T) {
+	t.Parallel()
+	config := ratelimit.DefaultConfig()
+	config.TrustProxy = true
+	limiter := NewRateLimiter(ratelimit.NewMemoryStore(), config)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Add("X-Forwarded-For", "1.1.1.1, 2.2.2.2")
+	if ip := limiter.clientIP(r); ip != "2.2.2.2" {
+		t.Fatalf("Expected 2.2.2.2, got %s", ip)
+	}
+	limiter.config.TrustProxy = false
+	if ip := limiter.clientIP(r); ip != "10.0.0.1" {
+		t.Fatalf("Expected 10.0.0.1, got %s", ip)
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/logging"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/problem"
+)
+
+// Recover turns a panic in a handler into a 500 response, logging the panic
+// and its stack. It must run inside Logger for the log line to carry the
+// request ID. If the response was already started, the connection is closed
+// instead, as the client would otherwise take a partial body for a full one.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &responseRecorder{ResponseWriter: w}
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+			logging.FromContext(r.Context()).Error("Panic serving request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"panic", fmt.Sprint(recovered),
+				"stack", string(debug.Stack()),
+			)
+			if recorder.status != 0 {
+				panic(http.ErrAbortHandler)
+			}
+			problem.Write(w, http.StatusInternalServerError, "internal server error")
+		}()
+		next.ServeHTTP(recorder, r)
+	})
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/problem"
+)
+
+func TestRecover(t *testing.T) {
+	t.Parallel()
+	handler := Logger(slog.Default())(Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Request-ID", "req-1")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+	if w.Header().Get("Content-Type") != problem.ContentType {
+		t.Fatalf("Expected Content-Type %s, got %s", problem.ContentType, w.Header().Get("Content-Type"))
+	}
+	var p problem.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("Failed to decode problem: %v", err)
+	}
+	if p.Status != http.StatusInternalServerError || p.RequestID != "req-1" {
+		t.Fatalf("Expected status 500 and request ID req-1, got %+v", p)
+	}
+}
+
+func TestRecover_ResponseStarted(t *testing.T) {
+	t.Parallel()
+	handler := Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		panic("boom")
+	}))
+	defer func() {
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Fatalf("Expected http.ErrAbortHandler, got %v", recovered)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
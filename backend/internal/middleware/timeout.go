@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/logging"
+)
+
+// ExtendDeadlines replaces the server's read and write timeouts for next with
+// timeout, for routes that stream file content and may legitimately take far
+// longer than other requests.
+func ExtendDeadlines(timeout time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		deadline := time.Now().Add(timeout)
+		controller := http.NewResponseController(w)
+		if err := controller.SetReadDeadline(deadline); err != nil {
+			logging.FromContext(r.Context()).Warn("Failed to extend read deadline", "error", err)
+		}
+		if err := controller.SetWriteDeadline(deadline); err != nil {
+			logging.FromContext(r.Context()).Warn("Failed to extend write deadline", "error", err)
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestExtendDeadlines(t *testing.T) {
+	t.Parallel()
+	// The deadlines must reach the connection through the writers wrapped by
+	// the other middleware
+	handler := Tracing(http.NewServeMux())(Logger(slog.Default())(Recover(http.HandlerFunc(ExtendDeadlines(time.Minute, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	})))))
+	server := httptest.NewUnstartedServer(handler)
+	server.Config.WriteTimeout = 50 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	response, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected response past the server's write timeout, got %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d", http.StatusNoContent, response.StatusCode)
+	}
+}
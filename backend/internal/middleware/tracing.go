@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Tracing starts a span for every request, continuing the trace of the
+// caller if it sent a traceparent header. Spans are named after the route of
+// routes the request matches. It must run outside Logger for log lines to
+// carry the trace ID.
+func Tracing(routes *http.ServeMux) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return otelhttp.NewHandler(next, "http.server",
+			otelhttp.WithSpanNameFormatter(func(operation string, r *http.Request) string {
+				return r.Method + " " + route(routes, r)
+			}),
+		)
+	}
+}
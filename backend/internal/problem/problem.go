@@ -0,0 +1,62 @@
+// Package problem writes error responses as RFC 9457 problem details, and
+// maps the errors of the db package to the status they are reported with.
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+)
+
+const ContentType = "application/problem+json"
+
+// Problem is a problem details object. Error repeats Detail for clients
+// written against the earlier {"error": message} responses.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	Error     string `json:"error"`
+}
+
+func New(status int, detail string) Problem {
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Error:  detail,
+	}
+}
+
+// Write responds with a problem of status. The request ID is taken from the
+// X-Request-ID response header, if the request has been given one.
+func Write(w http.ResponseWriter, status int, detail string) {
+	p := New(status, detail)
+	p.RequestID = w.Header().Get("X-Request-ID")
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// StatusOf returns the status err is reported with, if it is an error the
+// client can act on.
+func StatusOf(err error) (int, bool) {
+	var (
+		uploadNotFound      db.ErrUploadNotFound
+		partNotFound        db.ErrPartNotFound
+		shareNotFound       db.ErrShareNotFound
+		uploadAlreadyExists db.ErrUploadAlreadyExists
+	)
+	switch {
+	case errors.As(err, &uploadNotFound), errors.As(err, &partNotFound), errors.As(err, &shareNotFound):
+		return http.StatusNotFound, true
+	case errors.As(err, &uploadAlreadyExists):
+		return http.StatusConflict, true
+	}
+	return 0, false
+}
@@ -0,0 +1,59 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+	"github.com/google/uuid"
+)
+
+func TestStatusOf(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		err    error
+		status int
+		ok     bool
+	}{
+		{db.ErrUploadNotFound{UploadID: uuid.New()}, http.StatusNotFound, true},
+		{db.ErrPartNotFound{UploadID: uuid.New(), PartNumber: 1}, http.StatusNotFound, true},
+		{fmt.Errorf("wrapped: %w", db.ErrUploadAlreadyExists{UploadID: uuid.New()}), http.StatusConflict, true},
+		{errors.New("connection refused"), 0, false},
+	}
+	for _, c := range cases {
+		status, ok := StatusOf(c.err)
+		if status != c.status || ok != c.ok {
+			t.Fatalf("Expected %d, %t for %v, got %d, %t", c.status, c.ok, c.err, status, ok)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+	w := httptest.NewRecorder()
+	w.Header().Set("X-Request-ID", "req-1")
+	Write(w, http.StatusConflict, "upload is already completed")
+
+	if w.Header().Get("Content-Type") != ContentType {
+		t.Fatalf("Expected Content-Type %s, got %s", ContentType, w.Header().Get("Content-Type"))
+	}
+	var p Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("Failed to decode problem: %v", err)
+	}
+	expected := Problem{
+		Type:      "about:blank",
+		Title:     "Conflict",
+		Status:    http.StatusConflict,
+		Detail:    "upload is already completed",
+		RequestID: "req-1",
+		Error:     "upload is already completed",
+	}
+	if p != expected {
+		t.Fatalf("Expected %+v, got %+v", expected, p)
+	}
+}
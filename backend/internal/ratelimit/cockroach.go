@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DBStore keeps token buckets in CockroachDB, so that limits hold across
+// replicas.
+type DBStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewDBStore(pool *pgxpool.Pool) *DBStore {
+	return &DBStore{pool: pool}
+}
+
+func (s *DBStore) Take(ctx context.Context, key string, limit Limit, cost float64, force bool) (Result, error) {
+	ctx = db.WithConnPool(ctx, s.pool)
+	allowed, tokens, err := db.TakeRateLimitTokens(ctx, key, limit.Rate, limit.Burst, cost, force)
+	if err != nil {
+		return Result{}, err
+	}
+	return newResult(allowed, tokens, cost, limit), nil
+}
+
+// Sweep deletes buckets idle for longer than idle every interval until ctx is
+// done. idle should be long enough for any bucket to have refilled.
+func (s *DBStore) Sweep(ctx context.Context, interval time.Duration, idle time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		deleted, err := db.DeleteIdleRateLimitBuckets(db.WithConnPool(ctx, s.pool), time.Now().Add(-idle))
+		if err != nil {
+			slog.Error("Failed to delete idle rate limit buckets", "error", err)
+			continue
+		}
+		slog.Debug("Deleted idle rate limit buckets", "count", deleted)
+	}
+}
@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepThreshold is the number of buckets above which full buckets are
+// dropped, since they are the same as no bucket.
+const sweepThreshold = 10000
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updated).Seconds()
+	b.tokens = min(b.limit.Burst, b.tokens+elapsed*b.limit.Rate)
+	b.updated = now
+}
+
+// MemoryStore keeps token buckets in process, so each replica enforces limits
+// on its own.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}, now: time.Now}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, cost float64, force bool) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if len(s.buckets) > sweepThreshold {
+		s.sweep(now)
+	}
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: limit.Burst, updated: now}
+		s.buckets[key] = b
+	}
+	b.limit = limit
+	b.refill(now)
+	allowed := force || b.tokens >= cost
+	if allowed {
+		b.tokens -= cost
+	}
+	return newResult(allowed, b.tokens, cost, limit), nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		b.refill(now)
+		if b.tokens >= b.limit.Burst {
+			delete(s.buckets, key)
+		}
+	}
+}
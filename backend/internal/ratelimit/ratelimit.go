@@ -0,0 +1,150 @@
+// Package ratelimit implements token buckets, kept in process or shared
+// between replicas through the database.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket that holds up to Burst tokens and refills at Rate
+// tokens per second.
+type Limit struct {
+	Rate  float64
+	Burst float64
+}
+
+// Every returns a limit of n tokens per period, all of which may be used at
+// once.
+func Every(n float64, period time.Duration) Limit {
+	return Limit{Rate: n / period.Seconds(), Burst: n}
+}
+
+// ParseLimit parses a limit written as "<n>/<period>", such as "100/1m".
+func ParseLimit(value string) (Limit, error) {
+	count, period, ok := strings.Cut(value, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid limit %q, expected <n>/<period>", value)
+	}
+	n, err := strconv.ParseFloat(count, 64)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid limit %q: count must be positive", value)
+	}
+	duration, err := time.ParseDuration(period)
+	if err != nil || duration <= 0 {
+		return Limit{}, fmt.Errorf("invalid limit %q: period must be a positive duration", value)
+	}
+	return Every(n, duration), nil
+}
+
+// Result is the state of a bucket after a take.
+type Result struct {
+	Allowed bool
+	// Remaining is the number of tokens left, which is negative if the bucket
+	// is in debt.
+	Remaining float64
+	// RetryAfter is how long until a take of the same cost would succeed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// newResult works out the timings of a bucket left with tokens.
+func newResult(allowed bool, tokens float64, cost float64, limit Limit) Result {
+	result := Result{Allowed: allowed, Remaining: tokens}
+	if limit.Rate > 0 {
+		result.Reset = seconds((limit.Burst - tokens) / limit.Rate)
+		if !allowed {
+			result.RetryAfter = seconds((cost - tokens) / limit.Rate)
+		}
+	}
+	return result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(max(s, 0) * float64(time.Second)))
+}
+
+// Store keeps token buckets.
+type Store interface {
+	// Take removes cost tokens from the bucket under key if it holds at least
+	// that many. If force is set, they are removed regardless, and the bucket
+	// may go into debt.
+	Take(ctx context.Context, key string, limit Limit, cost float64, force bool) (Result, error)
+}
+
+const (
+	StoreMemory    = "memory"
+	StoreCockroach = "cockroach"
+)
+
+type Config struct {
+	Enabled bool
+	// Store is where buckets are kept, StoreMemory or StoreCockroach.
+	Store string
+	// Requests limits all requests of a caller.
+	Requests Limit
+	// Creates limits the uploads a caller may create.
+	Creates Limit
+	// Bytes limits the bytes a caller may stream through the API, both
+	// uploaded and downloaded.
+	Bytes Limit
+	// TrustProxy takes the client IP from X-Forwarded-For rather than the
+	// connection, for when the API runs behind a proxy.
+	TrustProxy bool
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Enabled:    true,
+		Store:      StoreMemory,
+		Requests:   Every(600, time.Minute),
+		Creates:    Every(60, time.Hour),
+		Bytes:      Every(10<<30, time.Hour),
+		TrustProxy: false,
+	}
+}
+
+// ConfigFromEnv reads RATE_LIMIT_ENABLED, RATE_LIMIT_STORE,
+// RATE_LIMIT_REQUESTS, RATE_LIMIT_CREATES, RATE_LIMIT_BYTES and
+// RATE_LIMIT_TRUST_PROXY, falling back to DefaultConfig for unset variables.
+func ConfigFromEnv(getenv func(string) string) (Config, error) {
+	config := DefaultConfig()
+	var err error
+	if value := getenv("RATE_LIMIT_ENABLED"); value != "" {
+		if config.Enabled, err = strconv.ParseBool(value); err != nil {
+			return config, fmt.Errorf("invalid RATE_LIMIT_ENABLED: %w", err)
+		}
+	}
+	if value := getenv("RATE_LIMIT_STORE"); value != "" {
+		if value != StoreMemory && value != StoreCockroach {
+			return config, fmt.Errorf("invalid RATE_LIMIT_STORE: %q", value)
+		}
+		config.Store = value
+	}
+	limits := []struct {
+		name  string
+		limit *Limit
+	}{
+		{"RATE_LIMIT_REQUESTS", &config.Requests},
+		{"RATE_LIMIT_CREATES", &config.Creates},
+		{"RATE_LIMIT_BYTES", &config.Bytes},
+	}
+	for _, l := range limits {
+		if value := getenv(l.name); value != "" {
+			if *l.limit, err = ParseLimit(value); err != nil {
+				return config, fmt.Errorf("invalid %s: %w", l.name, err)
+			}
+		}
+	}
+	if value := getenv("RATE_LIMIT_TRUST_PROXY"); value != "" {
+		if config.TrustProxy, err = strconv.ParseBool(value); err != nil {
+			return config, fmt.Errorf("invalid RATE_LIMIT_TRUST_PROXY: %w", err)
+		}
+	}
+	return config, nil
+}
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	t.Parallel()
+	limit, err := ParseLimit("120/1m")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if limit.Burst != 120 || limit.Rate != 2 {
+		t.Fatalf("Expected burst 120 and rate 2, got %+v", limit)
+	}
+	for _, value := range []string{"", "120", "0/1m", "-1/1m", "120/0s", "abc/1m", "120/abc"} {
+		if _, err := ParseLimit(value); err == nil {
+			t.Fatalf("Expected error for %q, got nil", value)
+		}
+	}
+}
+
+func TestMemoryStore_Take(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(0, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+	limit := Every(2, 2*time.Second)
+
+	for i := 0; i < 2; i++ {
+		result, _ := store.Take(ctx, "a", limit, 1, false)
+		if !result.Allowed {
+			t.Fatalf("Expected take %d to be allowed", i)
+		}
+	}
+	result, _ := store.Take(ctx, "a", limit, 1, false)
+	if result.Allowed {
+		t.Fatalf("Expected take from empty bucket to be denied")
+	}
+	if result.RetryAfter != time.Second {
+		t.Fatalf("Expected retry after 1s, got %s", result.RetryAfter)
+	}
+	if result, _ := store.Take(ctx, "b", limit, 1, false); !result.Allowed {
+		t.Fatalf("Expected take from other bucket to be allowed")
+	}
+
+	now = now.Add(time.Second)
+	if result, _ := store.Take(ctx, "a", limit, 1, false); !result.Allowed {
+		t.Fatalf("Expected take after refill to be allowed")
+	}
+}
+
+func TestMemoryStore_Debt(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(0, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+	limit := Every(10, 10*time.Second)
+
+	result, _ := store.Take(ctx, "a", limit, 30, true)
+	if !result.Allowed || result.Remaining != -20 {
+		t.Fatalf("Expected forced take to leave -20 tokens, got %+v", result)
+	}
+	result, _ = store.Take(ctx, "a", limit, 0, false)
+	if result.Allowed {
+		t.Fatalf("Expected bucket in debt to deny")
+	}
+	if result.RetryAfter != 20*time.Second {
+		t.Fatalf("Expected retry after 20s, got %s", result.RetryAfter)
+	}
+	if result.Reset != 30*time.Second {
+		t.Fatalf("Expected reset in 30s, got %s", result.Reset)
+	}
+}
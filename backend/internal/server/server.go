@@ -0,0 +1,194 @@
+// Package server runs the HTTP server and shuts it down gracefully, letting
+// transfers in flight finish before the process exits.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/health"
+)
+
+type Config struct {
+	Port string
+	// ReadHeaderTimeout bounds reading request headers on every route.
+	ReadHeaderTimeout time.Duration
+	// ControlTimeout bounds reading the request and writing the response of
+	// routes that do not transfer file content.
+	ControlTimeout time.Duration
+	// TransferTimeout replaces ControlTimeout on routes that upload or
+	// download file content.
+	TransferTimeout time.Duration
+	// IdleTimeout is how long keep-alive connections are kept between
+	// requests.
+	IdleTimeout time.Duration
+	// ShutdownDelay is how long the server keeps serving, with /health
+	// failing, after it is told to stop, so that load balancers stop sending
+	// it requests first.
+	ShutdownDelay time.Duration
+	// ShutdownTimeout is how long requests in flight are given to finish
+	// once the server stops accepting new ones.
+	ShutdownTimeout time.Duration
+	// ReadyTimeout bounds each dependency check of /ready.
+	ReadyTimeout time.Duration
+	// ReadyCacheTTL is how long the results of dependency checks are reused.
+	ReadyCacheTTL time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Port:              "8080",
+		ReadHeaderTimeout: 10 * time.Second,
+		ControlTimeout:    30 * time.Second,
+		TransferTimeout:   time.Hour,
+		IdleTimeout:       2 * time.Minute,
+		ShutdownDelay:     5 * time.Second,
+		ShutdownTimeout:   time.Minute,
+		ReadyTimeout:      2 * time.Second,
+		ReadyCacheTTL:     5 * time.Second,
+	}
+}
+
+// ConfigFromEnv reads BACKEND_PORT, SERVER_READ_HEADER_TIMEOUT,
+// SERVER_CONTROL_TIMEOUT, SERVER_TRANSFER_TIMEOUT, SERVER_IDLE_TIMEOUT,
+// SERVER_SHUTDOWN_DELAY, SERVER_SHUTDOWN_TIMEOUT, SERVER_READY_TIMEOUT and
+// SERVER_READY_CACHE_TTL, falling back to DefaultConfig for unset variables.
+func ConfigFromEnv(getenv func(string) string) (Config, error) {
+	config := DefaultConfig()
+	if value := getenv("BACKEND_PORT"); value != "" {
+		config.Port = value
+	}
+	durations := []struct {
+		name     string
+		duration *time.Duration
+	}{
+		{"SERVER_READ_HEADER_TIMEOUT", &config.ReadHeaderTimeout},
+		{"SERVER_CONTROL_TIMEOUT", &config.ControlTimeout},
+		{"SERVER_TRANSFER_TIMEOUT", &config.TransferTimeout},
+		{"SERVER_IDLE_TIMEOUT", &config.IdleTimeout},
+		{"SERVER_SHUTDOWN_DELAY", &config.ShutdownDelay},
+		{"SERVER_SHUTDOWN_TIMEOUT", &config.ShutdownTimeout},
+		{"SERVER_READY_TIMEOUT", &config.ReadyTimeout},
+		{"SERVER_READY_CACHE_TTL", &config.ReadyCacheTTL},
+	}
+	for _, d := range durations {
+		if value := getenv(d.name); value != "" {
+			duration, err := time.ParseDuration(value)
+			if err != nil || duration < 0 {
+				return config, fmt.Errorf("invalid %s: %q", d.name, value)
+			}
+			*d.duration = duration
+		}
+	}
+	return config, nil
+}
+
+// Server is an HTTP server that drains before it stops.
+type Server struct {
+	config   Config
+	checker  *health.Checker
+	draining atomic.Bool
+}
+
+// New creates a server whose readiness depends on the checks of checker.
+func New(config Config, checker *health.Checker) *Server {
+	return &Server{config: config, checker: checker}
+}
+
+type healthResponse struct {
+	Status   string                   `json:"status"`
+	Draining bool                     `json:"draining"`
+	Checks   map[string]health.Result `json:"checks"`
+}
+
+func writeHealth(w http.ResponseWriter, status int, response healthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Health handles GET /health, which tells whether the process is alive. It
+// fails only while the server is draining, as restarting does not fix a
+// dependency that is down. With ?verbose=1 it also reports the dependency
+// checks.
+func (s *Server) Health(w http.ResponseWriter, r *http.Request) {
+	draining := s.draining.Load()
+	status := http.StatusOK
+	if draining {
+		status = http.StatusServiceUnavailable
+	}
+	if verbose, _ := strconv.ParseBool(r.URL.Query().Get("verbose")); !verbose {
+		w.WriteHeader(status)
+		if draining {
+			w.Write([]byte("Shutting down"))
+		} else {
+			w.Write([]byte("OK"))
+		}
+		return
+	}
+	report := s.checker.Report(r.Context())
+	writeHealth(w, status, healthResponse{Status: report.Status, Draining: draining, Checks: report.Checks})
+}
+
+// Ready handles GET /ready, which tells whether the server should be sent
+// requests. It fails while the server is draining or a dependency is down.
+func (s *Server) Ready(w http.ResponseWriter, r *http.Request) {
+	draining := s.draining.Load()
+	report := s.checker.Report(r.Context())
+	status := http.StatusOK
+	if draining || !report.OK() {
+		status = http.StatusServiceUnavailable
+	}
+	writeHealth(w, status, healthResponse{Status: report.Status, Draining: draining, Checks: report.Checks})
+}
+
+// Run serves handler until ctx is done, then drains: /health fails for
+// ShutdownDelay, after which the server stops accepting requests and waits up
+// to ShutdownTimeout for those in flight. Requests still running after that
+// are cut off.
+func (s *Server) Run(ctx context.Context, handler http.Handler) error {
+	srv := &http.Server{
+		Addr:              ":" + s.config.Port,
+		Handler:           handler,
+		ReadHeaderTimeout: s.config.ReadHeaderTimeout,
+		ReadTimeout:       s.config.ControlTimeout,
+		WriteTimeout:      s.config.ControlTimeout,
+		IdleTimeout:       s.config.IdleTimeout,
+	}
+	errs := make(chan error, 1)
+	go func() {
+		slog.Info("Starting server", "port", s.config.Port)
+		errs <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Draining server", "delay", s.config.ShutdownDelay, "timeout", s.config.ShutdownTimeout)
+	s.draining.Store(true)
+	time.Sleep(s.config.ShutdownDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		slog.Warn("Requests still in flight after shutdown timeout, closing connections")
+		err = srv.Close()
+	}
+	if err != nil {
+		return err
+	}
+	slog.Info("Server stopped")
+	return nil
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/health"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("BACKEND_PORT", "9090")
+	t.Setenv("SERVER_TRANSFER_TIMEOUT", "2h")
+	t.Setenv("SERVER_SHUTDOWN_DELAY", "0s")
+
+	config, err := ConfigFromEnv(os.Getenv)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := DefaultConfig()
+	expected.Port = "9090"
+	expected.TransferTimeout = 2 * time.Hour
+	expected.ShutdownDelay = 0
+	if config != expected {
+		t.Fatalf("Expected %+v, got %+v", expected, config)
+	}
+
+	t.Setenv("SERVER_CONTROL_TIMEOUT", "soon")
+	if _, err := ConfigFromEnv(os.Getenv); err == nil {
+		t.Fatalf("Expected error for SERVER_CONTROL_TIMEOUT=soon, got nil")
+	}
+}
+
+func TestRun_Drain(t *testing.T) {
+	t.Parallel()
+	config := DefaultConfig()
+	config.Port = "0"
+	config.ShutdownDelay = 50 * time.Millisecond
+	s := New(config, health.NewChecker(time.Second, time.Second))
+
+	health := func() int {
+		w := httptest.NewRecorder()
+		s.Health(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+		return w.Code
+	}
+	if status := health(); status != http.StatusOK {
+		t.Fatalf("Expected status %d before shutdown, got %d", http.StatusOK, status)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(ctx, http.NotFoundHandler())
+	}()
+	cancel()
+	// Wait until the drain has begun
+	for i := 0; i < 100 && !s.draining.Load(); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if status := health(); status != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status %d while draining, got %d", http.StatusServiceUnavailable, status)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected server to stop")
+	}
+}
+
+func TestReady(t *testing.T) {
+	t.Parallel()
+	var down atomic.Bool
+	checker := health.NewChecker(time.Second, 0, health.Check{Name: "database", Check: func(ctx context.Context) error {
+		if down.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	}})
+	s := New(DefaultConfig(), checker)
+
+	ready := func() (int, map[string]any) {
+		w := httptest.NewRecorder()
+		s.Ready(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		var body map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return w.Code, body
+	}
+	if status, body := ready(); status != http.StatusOK || body["status"] != health.StatusOK {
+		t.Fatalf("Expected ready, got %d %v", status, body)
+	}
+
+	down.Store(true)
+	status, body := ready()
+	if status != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status %d with database down, got %d", http.StatusServiceUnavailable, status)
+	}
+	database := body["checks"].(map[string]any)["database"].(map[string]any)
+	if database["error"] != "connection refused" {
+		t.Fatalf("Expected database error to be reported, got %v", database)
+	}
+
+	// Liveness does not depend on the database
+	w := httptest.NewRecorder()
+	s.Health(w, httptest.NewRequest(http.MethodGet, "/health?verbose=1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected /health to stay %d, got %d", http.StatusOK, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "connection refused") {
+		t.Fatalf("Expected verbose /health to report checks, got %s", w.Body.String())
+	}
+}
@@ -3,85 +3,149 @@ package storage
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 
-	"cloud.google.com/go/storage"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/logging"
 )
 
-func Download(ctx context.Context, objectName string) ([]byte, error) {
-	bucket, err := Bucket()
-	if err != nil {
-		return nil, err
-	}
-	object := bucket.Object(objectName)
-	reader, err := object.NewReader(ctx)
-	if err != nil {
-		return nil, err
-	}
-	defer reader.Close()
+// Bucket runs operations on a store, recording a span and the duration of
+// each.
+type Bucket struct {
+	store ObjectStore
+}
 
-	data, err := io.ReadAll(reader)
-	if err != nil {
-		return nil, err
-	}
-	return data, nil
+func NewBucket(store ObjectStore) *Bucket {
+	return &Bucket{store: store}
 }
 
-func Upload(ctx context.Context, objectName string, data []byte) error {
-	bucket, err := Bucket()
-	if err != nil {
-		return err
-	}
-	object := bucket.Object(objectName)
-	writer := object.NewWriter(ctx)
-	writer.Write(data)
-	return writer.Close()
+// Store returns the store the bucket operates on.
+func (b *Bucket) Store() ObjectStore {
+	return b.store
+}
+
+// Ping checks that the store can be reached by looking up an object. A
+// missing object is fine, but a missing bucket or lack of access is not.
+func (b *Bucket) Ping(ctx context.Context) error {
+	_, err := b.Exists(ctx, probeObjectName)
+	return err
+}
+
+func (b *Bucket) Download(ctx context.Context, objectName string) ([]byte, error) {
+	ctx, op := startOperation(ctx, "download", objectName)
+	data, err := b.store.Download(ctx, objectName)
+	op.end(err)
+	return data, err
+}
+
+// NewRangeReader opens a ranged reader. The operation is timed until the
+// reader is closed, so it covers reading the body and not only opening it.
+func (b *Bucket) NewRangeReader(ctx context.Context, objectName string, offset int64, length int64) (io.ReadCloser, error) {
+	return openRangeReader(ctx, b.store, objectName, offset, length)
+}
+
+func (b *Bucket) DownloadTo(ctx context.Context, objectName string, w io.Writer, offset int64, length int64) (int64, error) {
+	ctx, op := startOperation(ctx, "download_to", objectName)
+	n, err := DownloadToIn(ctx, b.store, objectName, w, offset, length)
+	op.end(err)
+	return n, err
+}
+
+func (b *Bucket) Attrs(ctx context.Context, objectName string) (*ObjectAttrs, error) {
+	ctx, op := startOperation(ctx, "attrs", objectName)
+	attrs, err := b.store.Attrs(ctx, objectName)
+	op.end(err)
+	return attrs, err
+}
+
+func (b *Bucket) Upload(ctx context.Context, objectName string, data []byte) error {
+	ctx, op := startOperation(ctx, "upload", objectName)
+	err := b.store.Upload(ctx, objectName, data)
+	op.end(err)
+	return err
 }
 
-func Delete(ctx context.Context, objectName string) error {
-	bucket, err := Bucket()
-	if err != nil {
+// UploadFrom streams r into an object. The operation is timed until the
+// object is committed, so it includes time spent waiting on r.
+func (b *Bucket) UploadFrom(ctx context.Context, objectName string, r io.Reader, opts *UploadOptions) (int64, error) {
+	ctx, op := startOperation(ctx, "upload_from", objectName)
+	written, err := b.store.UploadFrom(ctx, objectName, r, opts)
+	op.end(err)
+	return written, err
+}
+
+func (b *Bucket) Delete(ctx context.Context, objectName string) error {
+	ctx, op := startOperation(ctx, "delete", objectName)
+	err := b.store.Delete(ctx, objectName)
+	op.end(err)
+	return err
+}
+
+func (b *Bucket) Exists(ctx context.Context, objectName string) (bool, error) {
+	ctx, op := startOperation(ctx, "exists", objectName)
+	exists, err := b.store.Exists(ctx, objectName)
+	op.end(err)
+	return exists, err
+}
+
+func (b *Bucket) Compose(ctx context.Context, dstObjectName string, srcObjectNames []string) error {
+	if err := validateComposeSources(srcObjectNames); err != nil {
 		return err
 	}
-	object := bucket.Object(objectName)
-	return object.Delete(ctx)
+	ctx, op := startOperation(ctx, "compose", dstObjectName)
+	err := b.store.Compose(ctx, dstObjectName, srcObjectNames)
+	op.end(err)
+	return err
 }
 
-func Exists(ctx context.Context, objectName string) (bool, error) {
-	bucket, err := Bucket()
-	if err != nil {
-		return false, err
-	}
-	object := bucket.Object(objectName)
-	_, err = object.NewReader(ctx)
-	if errors.Is(err, storage.ErrObjectNotExist) {
-		return false, nil
-	} else if err != nil {
-		return false, err
-	}
-	return true, nil
+func (b *Bucket) Copy(ctx context.Context, dstObjectName string, srcObjectName string) error {
+	ctx, op := startOperation(ctx, "copy", dstObjectName)
+	err := b.store.Copy(ctx, dstObjectName, srcObjectName)
+	op.end(err)
+	return err
 }
 
-func Compose(ctx context.Context, dstObjectName string, srcObjectNames []string) error {
-	if len(srcObjectNames) == 0 {
+func (b *Bucket) ComposeAll(ctx context.Context, dstObjectName string, srcObjectNames []string) error {
+	ctx, op := startOperation(ctx, "compose_all", dstObjectName)
+	err := ComposeAllIn(ctx, b.store, dstObjectName, srcObjectNames)
+	op.end(err)
+	return err
+}
+
+// ComposeAllIn concatenates any number of objects into dstObjectName. Sources
+// beyond the Compose limit are composed in batches into intermediate objects,
+// which are deleted once the destination is written.
+func ComposeAllIn(ctx context.Context, store ObjectStore, dstObjectName string, srcObjectNames []string) error {
+	switch {
+	case len(srcObjectNames) == 0:
 		return errors.New("no object names provided")
+	case len(srcObjectNames) == 1:
+		return store.Copy(ctx, dstObjectName, srcObjectNames[0])
+	case len(srcObjectNames) <= MaxComposeSources:
+		return store.Compose(ctx, dstObjectName, srcObjectNames)
 	}
-	if len(srcObjectNames) == 1 {
-		return errors.New("only one object name provided")
-	}
-	if len(srcObjectNames) > 32 {
-		return errors.New("only up to 32 object names can be provided")
-	}
-	bucket, err := Bucket()
-	if err != nil {
-		return err
-	}
-	dstObject := bucket.Object(dstObjectName)
-	srcObjects := make([]*storage.ObjectHandle, len(srcObjectNames))
-	for i, srcObjectName := range srcObjectNames {
-		srcObjects[i] = bucket.Object(srcObjectName)
+
+	intermediates := []string{}
+	defer func() {
+		for _, name := range intermediates {
+			if err := store.Delete(context.WithoutCancel(ctx), name); err != nil {
+				logging.FromContext(ctx).Error("Failed to delete intermediate object", "object_key", name, "error", err)
+			}
+		}
+	}()
+	batches := []string{}
+	for i := 0; i < len(srcObjectNames); i += MaxComposeSources {
+		batch := srcObjectNames[i:min(i+MaxComposeSources, len(srcObjectNames))]
+		if len(batch) == 1 {
+			batches = append(batches, batch[0])
+			continue
+		}
+		name := fmt.Sprintf("%s-compose-%d-%d", dstObjectName, len(srcObjectNames), i/MaxComposeSources)
+		if err := store.Compose(ctx, name, batch); err != nil {
+			return err
+		}
+		intermediates = append(intermediates, name)
+		batches = append(batches, name)
 	}
-	composer := dstObject.ComposerFrom(srcObjects...)
-	_, err = composer.Run(ctx)
-	return err
+	return ComposeAllIn(ctx, store, dstObjectName, batches)
 }
@@ -2,25 +2,41 @@ package storage
 
 import (
 	"context"
+	"os"
 	"strconv"
 	"sync"
 	"testing"
 )
 
+// newTestBucket creates a bucket on the store configured in the environment.
+func newTestBucket(t *testing.T) *Bucket {
+	config, err := ConfigFromEnv(os.Getenv)
+	if err != nil {
+		t.Fatalf("Failed to read storage config: %v", err)
+	}
+	store, err := New(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return NewBucket(store)
+}
+
 func TestUploadAndDownload(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
+	bucket := newTestBucket(t)
 
 	// Upload object
 	objectName := t.Name() + "-test-object"
 	uploadedData := []byte("This is a test object")
-	err := Upload(ctx, objectName, uploadedData)
+	err := bucket.Upload(ctx, objectName, uploadedData)
 	if err != nil {
 		t.Fatalf("Failed to upload object: %v", err)
 	}
 
 	// Download object
-	downloadedData, err := Download(ctx, objectName)
+	downloadedData, err := bucket.Download(ctx, objectName)
 	if err != nil {
 		t.Fatalf("Failed to download object: %v", err)
 	}
@@ -34,23 +50,24 @@ func TestUploadAndDownload(t *testing.T) {
 func TestDelete(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
+	bucket := newTestBucket(t)
 
 	// Upload object
 	objectName := t.Name() + "-test-object"
 	uploadedData := []byte("This object will be deleted")
-	err := Upload(ctx, objectName, uploadedData)
+	err := bucket.Upload(ctx, objectName, uploadedData)
 	if err != nil {
 		t.Fatalf("Failed to upload object: %v", err)
 	}
 
 	// Delete object
-	err = Delete(ctx, objectName)
+	err = bucket.Delete(ctx, objectName)
 	if err != nil {
 		t.Fatalf("Failed to delete object: %v", err)
 	}
 
 	// Try to download deleted object
-	_, err = Download(ctx, objectName)
+	_, err = bucket.Download(ctx, objectName)
 	if err == nil {
 		t.Fatalf("Expected error when downloading deleted object, but got none")
 	}
@@ -60,13 +77,14 @@ func TestDelete(t *testing.T) {
 func TestExists(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
+	bucket := newTestBucket(t)
 	objectName := t.Name() + "-test-object"
 
 	// Try deleting the object
-	Delete(ctx, objectName)
+	bucket.Delete(ctx, objectName)
 
 	// Initially, the object should not exist
-	exists, err := Exists(ctx, objectName)
+	exists, err := bucket.Exists(ctx, objectName)
 	if err != nil {
 		t.Fatalf("Failed to check if object exists: %v", err)
 	}
@@ -76,13 +94,13 @@ func TestExists(t *testing.T) {
 
 	// Upload object
 	uploadedData := []byte("This is a test object")
-	err = Upload(ctx, objectName, uploadedData)
+	err = bucket.Upload(ctx, objectName, uploadedData)
 	if err != nil {
 		t.Fatalf("Failed to upload object: %v", err)
 	}
 
 	// Now the object should exist
-	exists, err = Exists(ctx, objectName)
+	exists, err = bucket.Exists(ctx, objectName)
 	if err != nil {
 		t.Fatalf("Failed to check if object exists: %v", err)
 	}
@@ -93,11 +111,12 @@ func TestExists(t *testing.T) {
 
 func TestCompose(t *testing.T) {
 	ctx := context.Background()
+	bucket := newTestBucket(t)
 
 	t.Run("0 source object", func(t *testing.T) {
 		t.Parallel()
 		dstObjectName := t.Name() + "-dst-object"
-		err := Compose(ctx, dstObjectName, []string{})
+		err := bucket.Compose(ctx, dstObjectName, []string{})
 		if err == nil {
 			t.Fatalf("Expected error when composing with 0 source objects, but got none")
 		}
@@ -107,11 +126,11 @@ func TestCompose(t *testing.T) {
 		t.Parallel()
 		dstObjectName := t.Name() + "-dst-object"
 		srcObjectName := t.Name() + "-src-object-1"
-		err := Upload(ctx, srcObjectName, []byte("This is a test object"))
+		err := bucket.Upload(ctx, srcObjectName, []byte("This is a test object"))
 		if err != nil {
 			t.Fatalf("Failed to upload source object: %v", err)
 		}
-		err = Compose(ctx, dstObjectName, []string{srcObjectName})
+		err = bucket.Compose(ctx, dstObjectName, []string{srcObjectName})
 		if err == nil {
 			t.Fatalf("Expected error when composing with 1 source object, but got none")
 		}
@@ -131,7 +150,7 @@ func TestCompose(t *testing.T) {
 				defer wg.Done()
 				srcObjectName := t.Name() + "-src-object-" + strconv.Itoa(i)
 				data := []byte("This is test object " + strconv.Itoa(i))
-				err := Upload(ctx, srcObjectName, data)
+				err := bucket.Upload(ctx, srcObjectName, data)
 				if err != nil {
 					t.Errorf("Failed to upload source object %d: %v", i, err)
 				}
@@ -142,11 +161,11 @@ func TestCompose(t *testing.T) {
 			}(i)
 		}
 		wg.Wait()
-		err := Compose(ctx, dstObjectName, srcObjectNames)
+		err := bucket.Compose(ctx, dstObjectName, srcObjectNames)
 		if err != nil {
 			t.Fatalf("Failed to compose objects: %v", err)
 		}
-		composedData, err := Download(ctx, dstObjectName)
+		composedData, err := bucket.Download(ctx, dstObjectName)
 		if err != nil {
 			t.Fatalf("Failed to download composed object: %v", err)
 		}
@@ -167,7 +186,7 @@ func TestCompose(t *testing.T) {
 				defer wg.Done()
 				srcObjectName := t.Name() + "-src-object-" + strconv.Itoa(i)
 				data := []byte("This is test object " + strconv.Itoa(i))
-				err := Upload(ctx, srcObjectName, data)
+				err := bucket.Upload(ctx, srcObjectName, data)
 				if err != nil {
 					t.Errorf("Failed to upload source object %d: %v", i, err)
 				}
@@ -177,10 +196,40 @@ func TestCompose(t *testing.T) {
 			}(i)
 		}
 		wg.Wait()
-		err := Compose(ctx, dstObjectName, srcObjectNames)
+		err := bucket.Compose(ctx, dstObjectName, srcObjectNames)
 		if err == nil {
 			t.Fatalf("Expected error when composing with 33 source objects, but got none")
 		}
 	})
 
 }
+
+func TestComposeAll(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bucket := newTestBucket(t)
+	dstObjectName := t.Name() + "-dst-object"
+	srcObjectNames := make([]string, 40)
+	expectedData := []byte{}
+	for i := range srcObjectNames {
+		srcObjectNames[i] = t.Name() + "-src-object-" + strconv.Itoa(i)
+		data := []byte("This is test object " + strconv.Itoa(i))
+		err := bucket.Upload(ctx, srcObjectNames[i], data)
+		if err != nil {
+			t.Fatalf("Failed to upload source object %d: %v", i, err)
+		}
+		expectedData = append(expectedData, data...)
+	}
+
+	err := bucket.ComposeAll(ctx, dstObjectName, srcObjectNames)
+	if err != nil {
+		t.Fatalf("Failed to compose objects: %v", err)
+	}
+	composedData, err := bucket.Download(ctx, dstObjectName)
+	if err != nil {
+		t.Fatalf("Failed to download composed object: %v", err)
+	}
+	if string(composedData) != string(expectedData) {
+		t.Fatalf("Composed object data does not match expected data")
+	}
+}
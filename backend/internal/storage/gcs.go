@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/option"
+)
+
+// GCSStore keeps objects in a Google Cloud Storage bucket.
+type GCSStore struct {
+	client *storage.Client
+	bucket *storage.BucketHandle
+}
+
+func NewGCSStore(ctx context.Context, credentialsFile string, bucketName string) (*GCSStore, error) {
+	if credentialsFile == "" {
+		return nil, errors.New("BACKEND_GOOGLE_APPLICATION_CREDENTIALS is not set")
+	}
+	if bucketName == "" {
+		return nil, errors.New("GCLOUD_BUCKET_NAME is not set")
+	}
+	client, err := storage.NewClient(ctx, option.WithCredentialsFile(credentialsFile))
+	if err != nil {
+		return nil, err
+	}
+	return &GCSStore{client: client, bucket: client.Bucket(bucketName)}, nil
+}
+
+// gcsError translates errors from the GCS client into this package's errors.
+func gcsError(err error) error {
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return ErrObjectNotExist
+	}
+	return err
+}
+
+func (s *GCSStore) Upload(ctx context.Context, objectName string, data []byte) error {
+	_, err := s.UploadFrom(ctx, objectName, bytes.NewReader(data), nil)
+	return err
+}
+
+func (s *GCSStore) UploadFrom(ctx context.Context, objectName string, r io.Reader, opts *UploadOptions) (int64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	writer := s.bucket.Object(objectName).NewWriter(ctx)
+	if opts != nil {
+		writer.ContentType = opts.ContentType
+		writer.ContentEncoding = opts.ContentEncoding
+		writer.Metadata = opts.Metadata
+	}
+	written, err := io.Copy(writer, r)
+	if err != nil {
+		// Cancelling the context before Close aborts the upload.
+		cancel()
+		writer.Close()
+		return written, err
+	}
+	return written, writer.Close()
+}
+
+func (s *GCSStore) Download(ctx context.Context, objectName string) ([]byte, error) {
+	reader, err := s.bucket.Object(objectName).NewReader(ctx)
+	if err != nil {
+		return nil, gcsError(err)
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+func (s *GCSStore) NewRangeReader(ctx context.Context, objectName string, offset int64, length int64) (io.ReadCloser, error) {
+	reader, err := s.bucket.Object(objectName).NewRangeReader(ctx, offset, length)
+	if err != nil {
+		return nil, gcsError(err)
+	}
+	return reader, nil
+}
+
+func (s *GCSStore) Attrs(ctx context.Context, objectName string) (*ObjectAttrs, error) {
+	attrs, err := s.bucket.Object(objectName).Attrs(ctx)
+	if err != nil {
+		return nil, gcsError(err)
+	}
+	return &ObjectAttrs{
+		Name:            attrs.Name,
+		Size:            attrs.Size,
+		ContentType:     attrs.ContentType,
+		ContentEncoding: attrs.ContentEncoding,
+		Metadata:        attrs.Metadata,
+		Updated:         attrs.Updated,
+	}, nil
+}
+
+func (s *GCSStore) Delete(ctx context.Context, objectName string) error {
+	return gcsError(s.bucket.Object(objectName).Delete(ctx))
+}
+
+func (s *GCSStore) Exists(ctx context.Context, objectName string) (bool, error) {
+	_, err := s.bucket.Object(objectName).Attrs(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *GCSStore) Compose(ctx context.Context, dstObjectName string, srcObjectNames []string) error {
+	if err := validateComposeSources(srcObjectNames); err != nil {
+		return err
+	}
+	srcObjects := make([]*storage.ObjectHandle, len(srcObjectNames))
+	for i, srcObjectName := range srcObjectNames {
+		srcObjects[i] = s.bucket.Object(srcObjectName)
+	}
+	_, err := s.bucket.Object(dstObjectName).ComposerFrom(srcObjects...).Run(ctx)
+	return gcsError(err)
+}
+
+func (s *GCSStore) Copy(ctx context.Context, dstObjectName string, srcObjectName string) error {
+	_, err := s.bucket.Object(dstObjectName).CopierFrom(s.bucket.Object(srcObjectName)).Run(ctx)
+	return gcsError(err)
+}
+
+// SignedUploadURL returns a V4 signed URL for uploading objectName straight to
+// the bucket. The signing key and account are taken from the service account
+// credentials the store was created with.
+func (s *GCSStore) SignedUploadURL(ctx context.Context, objectName string, opts SignedUploadOptions) (*SignedUpload, error) {
+	expiresAt := time.Now().Add(opts.Expires)
+	contentLengthRange := fmt.Sprintf("x-goog-content-length-range:%d,%d", opts.ContentLength, opts.ContentLength)
+	signedURL, err := s.bucket.SignedURL(objectName, &storage.SignedURLOptions{
+		Scheme:      storage.SigningSchemeV4,
+		Method:      http.MethodPut,
+		Expires:     expiresAt,
+		ContentType: opts.ContentType,
+		Headers:     []string{contentLengthRange},
+	})
+	if err != nil {
+		return nil, err
+	}
+	headers := map[string]string{
+		"x-goog-content-length-range": fmt.Sprintf("%d,%d", opts.ContentLength, opts.ContentLength),
+	}
+	if opts.ContentType != "" {
+		headers["Content-Type"] = opts.ContentType
+	}
+	return &SignedUpload{URL: signedURL, Method: http.MethodPut, Headers: headers, ExpiresAt: expiresAt}, nil
+}
+
+func (s *GCSStore) Close() error {
+	return s.client.Close()
+}
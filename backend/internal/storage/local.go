@@ -0,0 +1,257 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore keeps objects as files in a directory. Writes go to a temporary
+// file that is renamed into place, so readers never see partial objects.
+// Content type, encoding and metadata are kept as JSON under attrsDir.
+type LocalStore struct {
+	dir string
+}
+
+func NewLocalStore(dir string) (*LocalStore, error) {
+	if dir == "" {
+		return nil, errors.New("STORAGE_LOCAL_DIR is not set")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalStore{dir: dir}, nil
+}
+
+const attrsDir = ".attrs"
+
+type localAttrs struct {
+	ContentType     string            `json:"content_type,omitempty"`
+	ContentEncoding string            `json:"content_encoding,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+}
+
+func (s *LocalStore) path(objectName string) (string, error) {
+	if objectName == "" || strings.Contains(objectName, "\x00") {
+		return "", fmt.Errorf("invalid object name: %q", objectName)
+	}
+	name := filepath.FromSlash(objectName)
+	if !filepath.IsLocal(name) || strings.SplitN(objectName, "/", 2)[0] == attrsDir {
+		return "", fmt.Errorf("invalid object name: %q", objectName)
+	}
+	return filepath.Join(s.dir, name), nil
+}
+
+func (s *LocalStore) attrsPath(objectName string) string {
+	return filepath.Join(s.dir, attrsDir, filepath.FromSlash(objectName)+".json")
+}
+
+func (s *LocalStore) readAttrs(objectName string) (localAttrs, error) {
+	var attrs localAttrs
+	data, err := os.ReadFile(s.attrsPath(objectName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return attrs, nil
+	} else if err != nil {
+		return attrs, err
+	}
+	return attrs, json.Unmarshal(data, &attrs)
+}
+
+// writeAttrs stores the attributes of an object, or removes them if opts is nil.
+func (s *LocalStore) writeAttrs(objectName string, opts *UploadOptions) error {
+	path := s.attrsPath(objectName)
+	if opts == nil {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(localAttrs{
+		ContentType:     opts.ContentType,
+		ContentEncoding: opts.ContentEncoding,
+		Metadata:        opts.Metadata,
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func localError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrObjectNotExist
+	}
+	return err
+}
+
+// write copies r into a temporary file and renames it to the object's path.
+func (s *LocalStore) write(ctx context.Context, objectName string, r io.Reader, opts *UploadOptions) (int64, error) {
+	path, err := s.path(objectName)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, contextReader{ctx: ctx, r: r})
+	if err != nil {
+		tmp.Close()
+		return written, err
+	}
+	if err := tmp.Close(); err != nil {
+		return written, err
+	}
+	if err := ctx.Err(); err != nil {
+		return written, err
+	}
+	if err := s.writeAttrs(objectName, opts); err != nil {
+		return written, err
+	}
+	return written, os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalStore) Upload(ctx context.Context, objectName string, data []byte) error {
+	_, err := s.write(ctx, objectName, bytes.NewReader(data), nil)
+	return err
+}
+
+func (s *LocalStore) UploadFrom(ctx context.Context, objectName string, r io.Reader, opts *UploadOptions) (int64, error) {
+	return s.write(ctx, objectName, r, opts)
+}
+
+func (s *LocalStore) Download(ctx context.Context, objectName string) ([]byte, error) {
+	path, err := s.path(objectName)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	return data, localError(err)
+}
+
+type fileRangeReader struct {
+	io.Reader
+	io.Closer
+}
+
+func (s *LocalStore) NewRangeReader(ctx context.Context, objectName string, offset int64, length int64) (io.ReadCloser, error) {
+	path, err := s.path(objectName)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, localError(err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if offset < 0 || offset > info.Size() {
+		file.Close()
+		return nil, errors.New("offset out of range")
+	}
+	if length < 0 {
+		length = info.Size() - offset
+	}
+	return withContext(ctx, fileRangeReader{Reader: io.NewSectionReader(file, offset, length), Closer: file}), nil
+}
+
+func (s *LocalStore) Attrs(ctx context.Context, objectName string) (*ObjectAttrs, error) {
+	path, err := s.path(objectName)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, localError(err)
+	}
+	attrs, err := s.readAttrs(objectName)
+	if err != nil {
+		return nil, err
+	}
+	return &ObjectAttrs{
+		Name:            objectName,
+		Size:            info.Size(),
+		ContentType:     attrs.ContentType,
+		ContentEncoding: attrs.ContentEncoding,
+		Metadata:        attrs.Metadata,
+		Updated:         info.ModTime(),
+	}, nil
+}
+
+func (s *LocalStore) Delete(ctx context.Context, objectName string) error {
+	path, err := s.path(objectName)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return localError(err)
+	}
+	return s.writeAttrs(objectName, nil)
+}
+
+func (s *LocalStore) Exists(ctx context.Context, objectName string) (bool, error) {
+	_, err := s.Attrs(ctx, objectName)
+	if errors.Is(err, ErrObjectNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *LocalStore) Compose(ctx context.Context, dstObjectName string, srcObjectNames []string) error {
+	if err := validateComposeSources(srcObjectNames); err != nil {
+		return err
+	}
+	readers := make([]io.Reader, len(srcObjectNames))
+	for i, srcObjectName := range srcObjectNames {
+		reader, err := s.NewRangeReader(ctx, srcObjectName, 0, -1)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		readers[i] = reader
+	}
+	_, err := s.write(ctx, dstObjectName, io.MultiReader(readers...), nil)
+	return err
+}
+
+func (s *LocalStore) Copy(ctx context.Context, dstObjectName string, srcObjectName string) error {
+	attrs, err := s.readAttrs(srcObjectName)
+	if err != nil {
+		return err
+	}
+	reader, err := s.NewRangeReader(ctx, srcObjectName, 0, -1)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	_, err = s.write(ctx, dstObjectName, reader, &UploadOptions{
+		ContentType:     attrs.ContentType,
+		ContentEncoding: attrs.ContentEncoding,
+		Metadata:        attrs.Metadata,
+	})
+	return err
+}
+
+func (s *LocalStore) Close() error {
+	return nil
+}
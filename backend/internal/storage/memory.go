@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps objects in process memory. It is meant for tests and local
+// development.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+// memoryObject data is never modified once stored, so it can be read without
+// holding the lock.
+type memoryObject struct {
+	data  []byte
+	attrs ObjectAttrs
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{objects: map[string]memoryObject{}}
+}
+
+func (s *MemoryStore) get(objectName string) (memoryObject, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	object, ok := s.objects[objectName]
+	if !ok {
+		return memoryObject{}, ErrObjectNotExist
+	}
+	return object, nil
+}
+
+func (s *MemoryStore) put(objectName string, data []byte, opts *UploadOptions) {
+	attrs := ObjectAttrs{Name: objectName, Size: int64(len(data)), Updated: time.Now()}
+	if opts != nil {
+		attrs.ContentType = opts.ContentType
+		attrs.ContentEncoding = opts.ContentEncoding
+		attrs.Metadata = maps.Clone(opts.Metadata)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[objectName] = memoryObject{data: data, attrs: attrs}
+}
+
+func (s *MemoryStore) Upload(ctx context.Context, objectName string, data []byte) error {
+	_, err := s.UploadFrom(ctx, objectName, bytes.NewReader(data), nil)
+	return err
+}
+
+func (s *MemoryStore) UploadFrom(ctx context.Context, objectName string, r io.Reader, opts *UploadOptions) (int64, error) {
+	var buf bytes.Buffer
+	written, err := io.Copy(&buf, contextReader{ctx: ctx, r: r})
+	if err != nil {
+		return written, err
+	}
+	if err := ctx.Err(); err != nil {
+		return written, err
+	}
+	s.put(objectName, buf.Bytes(), opts)
+	return written, nil
+}
+
+func (s *MemoryStore) Download(ctx context.Context, objectName string) ([]byte, error) {
+	object, err := s.get(objectName)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Clone(object.data), nil
+}
+
+func (s *MemoryStore) NewRangeReader(ctx context.Context, objectName string, offset int64, length int64) (io.ReadCloser, error) {
+	object, err := s.get(objectName)
+	if err != nil {
+		return nil, err
+	}
+	data, err := sliceRange(object.data, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	return withContext(ctx, io.NopCloser(bytes.NewReader(data))), nil
+}
+
+func (s *MemoryStore) Attrs(ctx context.Context, objectName string) (*ObjectAttrs, error) {
+	object, err := s.get(objectName)
+	if err != nil {
+		return nil, err
+	}
+	attrs := object.attrs
+	attrs.Metadata = maps.Clone(attrs.Metadata)
+	return &attrs, nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, objectName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.objects[objectName]; !ok {
+		return ErrObjectNotExist
+	}
+	delete(s.objects, objectName)
+	return nil
+}
+
+func (s *MemoryStore) Exists(ctx context.Context, objectName string) (bool, error) {
+	_, err := s.get(objectName)
+	if errors.Is(err, ErrObjectNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *MemoryStore) Compose(ctx context.Context, dstObjectName string, srcObjectNames []string) error {
+	if err := validateComposeSources(srcObjectNames); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, srcObjectName := range srcObjectNames {
+		object, err := s.get(srcObjectName)
+		if err != nil {
+			return err
+		}
+		buf.Write(object.data)
+	}
+	s.put(dstObjectName, buf.Bytes(), nil)
+	return nil
+}
+
+func (s *MemoryStore) Copy(ctx context.Context, dstObjectName string, srcObjectName string) error {
+	object, err := s.get(srcObjectName)
+	if err != nil {
+		return err
+	}
+	s.put(dstObjectName, object.data, &UploadOptions{
+		ContentType:     object.attrs.ContentType,
+		ContentEncoding: object.attrs.ContentEncoding,
+		Metadata:        object.attrs.Metadata,
+	})
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// sliceRange returns length bytes of data starting at offset, or the rest of
+// data if length is negative.
+func sliceRange(data []byte, offset int64, length int64) ([]byte, error) {
+	if offset < 0 || offset > int64(len(data)) {
+		return nil, errors.New("offset out of range")
+	}
+	end := int64(len(data))
+	if length >= 0 {
+		end = min(offset+length, end)
+	}
+	return data[offset:end], nil
+}
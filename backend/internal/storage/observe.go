@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/metrics"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// operation is a storage operation being traced and timed.
+type operation struct {
+	name  string
+	start time.Time
+	span  trace.Span
+}
+
+func startOperation(ctx context.Context, name string, objectName string) (context.Context, *operation) {
+	ctx, span := tracing.Tracer().Start(ctx, "storage."+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("storage.object", objectName)),
+	)
+	return ctx, &operation{name: name, start: time.Now(), span: span}
+}
+
+// end records the outcome of the operation. A missing object is not marked
+// as an error on the span, since it is often expected.
+func (o *operation) end(err error) {
+	notFound := errors.Is(err, ErrObjectNotExist)
+	metrics.ObserveStorageOperation(o.name, o.start, err, notFound)
+	if err != nil && !notFound {
+		o.span.RecordError(err)
+		o.span.SetStatus(codes.Error, err.Error())
+	}
+	o.span.End()
+}
+
+// observedReader ends its operation when it is closed, so that a ranged read
+// is timed until its body has been read rather than only until it opens. The
+// first read error other than io.EOF is recorded as the outcome.
+type observedReader struct {
+	io.ReadCloser
+	op     *operation
+	err    error
+	closed bool
+}
+
+// openRangeReader opens a ranged reader whose operation lasts until the reader
+// is closed.
+func openRangeReader(ctx context.Context, store ObjectStore, objectName string, offset int64, length int64) (io.ReadCloser, error) {
+	ctx, op := startOperation(ctx, "range_read", objectName)
+	reader, err := store.NewRangeReader(ctx, objectName, offset, length)
+	if err != nil {
+		op.end(err)
+		return nil, err
+	}
+	return &observedReader{ReadCloser: reader, op: op}, nil
+}
+
+func (r *observedReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+	return n, err
+}
+
+func (r *observedReader) Close() error {
+	err := r.ReadCloser.Close()
+	if !r.closed {
+		r.closed = true
+		if r.err != nil {
+			r.op.end(r.err)
+		} else {
+			r.op.end(err)
+		}
+	}
+	return err
+}
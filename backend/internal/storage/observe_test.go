@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/Yongbeom-Kim/transfer/backend/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func rangeReadCount(t *testing.T) uint64 {
+	t.Helper()
+	var m dto.Metric
+	observer := metrics.StorageOperationDuration.WithLabelValues("range_read", "ok")
+	if err := observer.(prometheus.Metric).Write(&m); err != nil {
+		t.Fatalf("Failed to read metric: %v", err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestBucketRangeReaderObservedOnClose(t *testing.T) {
+	ctx := context.Background()
+	bucket := NewBucket(NewMemoryStore())
+	if err := bucket.Upload(ctx, "object", []byte("0123456789")); err != nil {
+		t.Fatalf("Failed to upload object: %v", err)
+	}
+
+	before := rangeReadCount(t)
+	reader, err := bucket.NewRangeReader(ctx, "object", 0, -1)
+	if err != nil {
+		t.Fatalf("Failed to open range reader: %v", err)
+	}
+	if _, err := io.ReadAll(reader); err != nil {
+		t.Fatalf("Failed to read object: %v", err)
+	}
+	if count := rangeReadCount(t); count != before {
+		t.Fatalf("Expected no observation before close, got %d", count-before)
+	}
+	reader.Close()
+	reader.Close()
+	if count := rangeReadCount(t); count != before+1 {
+		t.Fatalf("Expected 1 observation after close, got %d", count-before)
+	}
+}
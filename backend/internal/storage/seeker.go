@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ObjectReadSeeker reads an object of known size through ranged readers. A
+// reader is only opened on the first Read after a Seek, so seeking is free and
+// only the requested ranges are streamed, which is what http.ServeContent needs
+// to serve Range requests.
+type ObjectReadSeeker struct {
+	ctx        context.Context
+	store      ObjectStore
+	objectName string
+	size       int64
+	offset     int64
+	reader     io.ReadCloser
+}
+
+func NewObjectReadSeeker(ctx context.Context, store ObjectStore, objectName string, size int64) *ObjectReadSeeker {
+	return &ObjectReadSeeker{ctx: ctx, store: store, objectName: objectName, size: size}
+}
+
+func (s *ObjectReadSeeker) Read(p []byte) (int, error) {
+	if s.offset >= s.size {
+		return 0, io.EOF
+	}
+	if s.reader == nil {
+		reader, err := openRangeReader(s.ctx, s.store, s.objectName, s.offset, s.size-s.offset)
+		if err != nil {
+			return 0, err
+		}
+		s.reader = reader
+	}
+	n, err := s.reader.Read(p)
+	s.offset += int64(n)
+	return n, err
+}
+
+func (s *ObjectReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.offset
+	case io.SeekEnd:
+		offset += s.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	if offset != s.offset {
+		s.closeReader()
+		s.offset = offset
+	}
+	return offset, nil
+}
+
+func (s *ObjectReadSeeker) Close() error {
+	return s.closeReader()
+}
+
+func (s *ObjectReadSeeker) closeReader() error {
+	if s.reader == nil {
+		return nil
+	}
+	err := s.reader.Close()
+	s.reader = nil
+	return err
+}
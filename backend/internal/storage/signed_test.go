@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHMACSigner(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	signer := NewHMACSigner(NewMemoryStore(), []byte("key"), "http://localhost:8080/storage/")
+	signed, err := signer.SignedUploadURL(ctx, "upload-1-0", SignedUploadOptions{
+		ContentType:   "application/octet-stream",
+		ContentLength: 10,
+		Expires:       time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Failed to sign URL: %v", err)
+	}
+	if signed.Method != "PUT" {
+		t.Fatalf("Expected method PUT, got %s", signed.Method)
+	}
+	if signed.Headers["Content-Type"] != "application/octet-stream" {
+		t.Fatalf("Expected Content-Type header, got %v", signed.Headers)
+	}
+	u, err := url.Parse(signed.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse signed URL: %v", err)
+	}
+	if !strings.HasPrefix(signed.URL, "http://localhost:8080/storage/upload-1-0?") {
+		t.Fatalf("Expected URL under the base URL, got %s", signed.URL)
+	}
+
+	length, err := signer.VerifyUpload("upload-1-0", u.Query(), "application/octet-stream", time.Now())
+	if err != nil {
+		t.Fatalf("Expected valid signature, got %v", err)
+	}
+	if length != 10 {
+		t.Fatalf("Expected length 10, got %d", length)
+	}
+
+	if _, err := signer.VerifyUpload("upload-1-1", u.Query(), "application/octet-stream", time.Now()); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("Expected ErrInvalidSignature for another object, got %v", err)
+	}
+	if _, err := signer.VerifyUpload("upload-1-0", u.Query(), "text/plain", time.Now()); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("Expected ErrInvalidSignature for another content type, got %v", err)
+	}
+	tampered := u.Query()
+	tampered.Set("length", "11")
+	if _, err := signer.VerifyUpload("upload-1-0", tampered, "application/octet-stream", time.Now()); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("Expected ErrInvalidSignature for a changed length, got %v", err)
+	}
+	if _, err := signer.VerifyUpload("upload-1-0", u.Query(), "application/octet-stream", time.Now().Add(2*time.Minute)); !errors.Is(err, ErrSignatureExpired) {
+		t.Fatalf("Expected ErrSignatureExpired, got %v", err)
+	}
+	other := NewHMACSigner(NewMemoryStore(), []byte("other key"), "http://localhost:8080/storage")
+	if _, err := other.VerifyUpload("upload-1-0", u.Query(), "application/octet-stream", time.Now()); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("Expected ErrInvalidSignature for another key, got %v", err)
+	}
+}
+
+func TestHMACSignerStore(t *testing.T) {
+	t.Parallel()
+	testObjectStore(t, NewHMACSigner(NewMemoryStore(), []byte("key"), "/storage"))
+}
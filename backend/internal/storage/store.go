@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// MaxComposeSources is the largest number of objects Compose accepts.
+const MaxComposeSources = 32
+
+var ErrObjectNotExist = errors.New("storage: object doesn't exist")
+
+type ObjectAttrs struct {
+	Name            string
+	Size            int64
+	ContentType     string
+	ContentEncoding string
+	Metadata        map[string]string
+	Updated         time.Time
+}
+
+// UploadOptions sets attributes on a new object. A nil *UploadOptions leaves
+// them empty.
+type UploadOptions struct {
+	ContentType     string
+	ContentEncoding string
+	Metadata        map[string]string
+}
+
+// ObjectStore is a flat namespace of immutable objects. Writing an object
+// replaces it as a whole, and reading a missing object fails with
+// ErrObjectNotExist.
+type ObjectStore interface {
+	Upload(ctx context.Context, objectName string, data []byte) error
+	// UploadFrom streams r into the object until EOF and returns the number of
+	// bytes written. The object is not created if reading or writing fails or
+	// ctx is cancelled before the upload finishes.
+	UploadFrom(ctx context.Context, objectName string, r io.Reader, opts *UploadOptions) (int64, error)
+	Download(ctx context.Context, objectName string) ([]byte, error)
+	// NewRangeReader streams length bytes of the object starting at offset. A
+	// negative length reads to the end of the object.
+	NewRangeReader(ctx context.Context, objectName string, offset int64, length int64) (io.ReadCloser, error)
+	Attrs(ctx context.Context, objectName string) (*ObjectAttrs, error)
+	Delete(ctx context.Context, objectName string) error
+	Exists(ctx context.Context, objectName string) (bool, error)
+	// Compose concatenates between 2 and MaxComposeSources objects, in order,
+	// into dstObjectName.
+	Compose(ctx context.Context, dstObjectName string, srcObjectNames []string) error
+	Copy(ctx context.Context, dstObjectName string, srcObjectName string) error
+	Close() error
+}
+
+const (
+	BackendGCS    = "gcs"
+	BackendLocal  = "local"
+	BackendMemory = "memory"
+)
+
+type Config struct {
+	// Backend is BackendGCS, BackendLocal or BackendMemory.
+	Backend string
+	// CredentialsFile and BucketName locate the bucket of the gcs backend.
+	CredentialsFile string
+	BucketName      string
+	// LocalDir is where the local backend keeps objects.
+	LocalDir string
+	// SigningKey signs the URLs of the local and memory backends, which cannot
+	// sign URLs themselves. If it is empty a random key is used, so URLs are
+	// only valid on the replica that signed them.
+	SigningKey []byte
+	// SignedURLBase is where the local and memory backends' URLs point.
+	SignedURLBase string
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Backend:       BackendGCS,
+		SignedURLBase: "/storage",
+	}
+}
+
+// ConfigFromEnv reads STORAGE_BACKEND, BACKEND_GOOGLE_APPLICATION_CREDENTIALS,
+// GCLOUD_BUCKET_NAME, STORAGE_LOCAL_DIR, STORAGE_SIGNING_KEY and
+// STORAGE_SIGNED_URL_BASE, falling back to DefaultConfig for unset variables.
+// The variables the selected backend needs must be set.
+func ConfigFromEnv(getenv func(string) string) (Config, error) {
+	config := DefaultConfig()
+	if value := getenv("STORAGE_BACKEND"); value != "" {
+		config.Backend = value
+	}
+	config.CredentialsFile = getenv("BACKEND_GOOGLE_APPLICATION_CREDENTIALS")
+	config.BucketName = getenv("GCLOUD_BUCKET_NAME")
+	config.LocalDir = getenv("STORAGE_LOCAL_DIR")
+	config.SigningKey = []byte(getenv("STORAGE_SIGNING_KEY"))
+	if value := getenv("STORAGE_SIGNED_URL_BASE"); value != "" {
+		config.SignedURLBase = value
+	}
+
+	switch config.Backend {
+	case BackendGCS:
+		if config.CredentialsFile == "" {
+			return config, errors.New("BACKEND_GOOGLE_APPLICATION_CREDENTIALS is not set")
+		}
+		if config.BucketName == "" {
+			return config, errors.New("GCLOUD_BUCKET_NAME is not set")
+		}
+	case BackendLocal:
+		if config.LocalDir == "" {
+			return config, errors.New("STORAGE_LOCAL_DIR is not set")
+		}
+	case BackendMemory:
+	default:
+		return config, fmt.Errorf("invalid STORAGE_BACKEND: %q", config.Backend)
+	}
+	return config, nil
+}
+
+// New creates the store selected by config.Backend: "gcs" keeps objects in a
+// Google Cloud Storage bucket, "local" in a directory and "memory" in process.
+func New(ctx context.Context, config Config) (ObjectStore, error) {
+	switch config.Backend {
+	case BackendGCS:
+		return NewGCSStore(ctx, config.CredentialsFile, config.BucketName)
+	case BackendLocal:
+		store, err := NewLocalStore(config.LocalDir)
+		if err != nil {
+			return nil, err
+		}
+		return newHMACSigner(store, config), nil
+	case BackendMemory:
+		return newHMACSigner(NewMemoryStore(), config), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %q", config.Backend)
+	}
+}
+
+// newHMACSigner wraps a store that cannot sign URLs itself.
+func newHMACSigner(store ObjectStore, config Config) *HMACSigner {
+	key := config.SigningKey
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		rand.Read(key)
+	}
+	return NewHMACSigner(store, key, config.SignedURLBase)
+}
+
+// probeObjectName is looked up by Ping. It need not exist.
+const probeObjectName = ".ready-probe"
+
+func validateComposeSources(srcObjectNames []string) error {
+	if len(srcObjectNames) == 0 {
+		return errors.New("no object names provided")
+	}
+	if len(srcObjectNames) == 1 {
+		return errors.New("only one object name provided")
+	}
+	if len(srcObjectNames) > MaxComposeSources {
+		return fmt.Errorf("only up to %d object names can be provided", MaxComposeSources)
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+)
+
+// testObjectStore runs the behaviour every ObjectStore must share.
+func testObjectStore(t *testing.T, store ObjectStore) {
+	ctx := context.Background()
+
+	t.Run("upload and download", func(t *testing.T) {
+		err := store.Upload(ctx, "object", []byte("This is a test object"))
+		if err != nil {
+			t.Fatalf("Failed to upload object: %v", err)
+		}
+		data, err := store.Download(ctx, "object")
+		if err != nil {
+			t.Fatalf("Failed to download object: %v", err)
+		}
+		if string(data) != "This is a test object" {
+			t.Fatalf("Downloaded data does not match uploaded data. Got: %s", string(data))
+		}
+	})
+
+	t.Run("range reader", func(t *testing.T) {
+		err := store.Upload(ctx, "ranged", []byte("0123456789"))
+		if err != nil {
+			t.Fatalf("Failed to upload object: %v", err)
+		}
+		reader, err := store.NewRangeReader(ctx, "ranged", 2, 3)
+		if err != nil {
+			t.Fatalf("Failed to open range reader: %v", err)
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("Failed to read range: %v", err)
+		}
+		if string(data) != "234" {
+			t.Fatalf("Expected 234, got %s", string(data))
+		}
+	})
+
+	t.Run("attrs", func(t *testing.T) {
+		written, err := store.UploadFrom(ctx, "attrs", io.LimitReader(zeroReader{}, 100), &UploadOptions{
+			ContentType:     "text/plain",
+			ContentEncoding: "identity",
+			Metadata:        map[string]string{"key": "value"},
+		})
+		if err != nil {
+			t.Fatalf("Failed to upload object: %v", err)
+		}
+		if written != 100 {
+			t.Fatalf("Expected 100 bytes written, got %d", written)
+		}
+		attrs, err := store.Attrs(ctx, "attrs")
+		if err != nil {
+			t.Fatalf("Failed to get attrs: %v", err)
+		}
+		if attrs.Size != 100 {
+			t.Fatalf("Expected size 100, got %d", attrs.Size)
+		}
+		if attrs.ContentType != "text/plain" || attrs.ContentEncoding != "identity" {
+			t.Fatalf("Expected content type and encoding to be kept, got %q, %q", attrs.ContentType, attrs.ContentEncoding)
+		}
+		if attrs.Metadata["key"] != "value" {
+			t.Fatalf("Expected metadata to be kept, got %v", attrs.Metadata)
+		}
+	})
+
+	t.Run("cancelled upload", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		r := io.MultiReader(io.LimitReader(zeroReader{}, 10), cancelReader{cancel}, io.LimitReader(zeroReader{}, 10))
+		_, err := store.UploadFrom(ctx, "cancelled", r, nil)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected context.Canceled, got %v", err)
+		}
+		exists, err := store.Exists(context.Background(), "cancelled")
+		if err != nil || exists {
+			t.Fatalf("Expected cancelled upload not to create the object, got %v, %v", exists, err)
+		}
+	})
+
+	t.Run("download to", func(t *testing.T) {
+		err := store.Upload(ctx, "download-to", []byte("0123456789"))
+		if err != nil {
+			t.Fatalf("Failed to upload object: %v", err)
+		}
+		var buf bytes.Buffer
+		written, err := DownloadToIn(ctx, store, "download-to", &buf, 5, -1)
+		if err != nil {
+			t.Fatalf("Failed to download object: %v", err)
+		}
+		if written != 5 || buf.String() != "56789" {
+			t.Fatalf("Expected 56789, got %d bytes: %s", written, buf.String())
+		}
+	})
+
+	t.Run("delete and exists", func(t *testing.T) {
+		err := store.Upload(ctx, "deleted", []byte("This object will be deleted"))
+		if err != nil {
+			t.Fatalf("Failed to upload object: %v", err)
+		}
+		exists, err := store.Exists(ctx, "deleted")
+		if err != nil || !exists {
+			t.Fatalf("Expected object to exist, got %v, %v", exists, err)
+		}
+		err = store.Delete(ctx, "deleted")
+		if err != nil {
+			t.Fatalf("Failed to delete object: %v", err)
+		}
+		exists, err = store.Exists(ctx, "deleted")
+		if err != nil || exists {
+			t.Fatalf("Expected object not to exist, got %v, %v", exists, err)
+		}
+		_, err = store.Download(ctx, "deleted")
+		if !errors.Is(err, ErrObjectNotExist) {
+			t.Fatalf("Expected ErrObjectNotExist, got %v", err)
+		}
+		err = store.Delete(ctx, "deleted")
+		if !errors.Is(err, ErrObjectNotExist) {
+			t.Fatalf("Expected ErrObjectNotExist when deleting twice, got %v", err)
+		}
+	})
+
+	t.Run("failed upload", func(t *testing.T) {
+		_, err := store.UploadFrom(ctx, "failed", io.MultiReader(io.LimitReader(zeroReader{}, 10), errReader{}), nil)
+		if err == nil {
+			t.Fatalf("Expected error from failing reader, got none")
+		}
+		exists, err := store.Exists(ctx, "failed")
+		if err != nil || exists {
+			t.Fatalf("Expected failed upload not to create the object, got %v, %v", exists, err)
+		}
+	})
+
+	t.Run("compose and copy", func(t *testing.T) {
+		srcObjectNames := make([]string, 40)
+		expectedData := []byte{}
+		for i := range srcObjectNames {
+			srcObjectNames[i] = "compose-src-" + strconv.Itoa(i)
+			data := []byte("This is test object " + strconv.Itoa(i))
+			err := store.Upload(ctx, srcObjectNames[i], data)
+			if err != nil {
+				t.Fatalf("Failed to upload source object %d: %v", i, err)
+			}
+			expectedData = append(expectedData, data...)
+		}
+
+		err := store.Compose(ctx, "compose-dst", srcObjectNames)
+		if err == nil {
+			t.Fatalf("Expected error when composing with 40 source objects, but got none")
+		}
+		err = ComposeAllIn(ctx, store, "compose-dst", srcObjectNames)
+		if err != nil {
+			t.Fatalf("Failed to compose objects: %v", err)
+		}
+		err = store.Copy(ctx, "compose-copy", "compose-dst")
+		if err != nil {
+			t.Fatalf("Failed to copy object: %v", err)
+		}
+		data, err := store.Download(ctx, "compose-copy")
+		if err != nil {
+			t.Fatalf("Failed to download composed object: %v", err)
+		}
+		if string(data) != string(expectedData) {
+			t.Fatalf("Composed object data does not match expected data")
+		}
+	})
+
+	t.Run("read seeker", func(t *testing.T) {
+		err := store.Upload(ctx, "seeker", []byte("0123456789"))
+		if err != nil {
+			t.Fatalf("Failed to upload object: %v", err)
+		}
+		seeker := NewObjectReadSeeker(ctx, store, "seeker", 10)
+		defer seeker.Close()
+		if _, err := seeker.Seek(-4, io.SeekEnd); err != nil {
+			t.Fatalf("Failed to seek: %v", err)
+		}
+		data, err := io.ReadAll(seeker)
+		if err != nil {
+			t.Fatalf("Failed to read: %v", err)
+		}
+		if string(data) != "6789" {
+			t.Fatalf("Expected 6789, got %s", string(data))
+		}
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+	testObjectStore(t, NewMemoryStore())
+}
+
+func TestLocalStore(t *testing.T) {
+	t.Parallel()
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create local store: %v", err)
+	}
+	testObjectStore(t, store)
+
+	if err := store.Upload(context.Background(), "../escape", []byte("data")); err == nil {
+		t.Fatalf("Expected error for object name outside the directory, got none")
+	}
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+type errReader struct{}
+
+func (errReader) Read(p []byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+// cancelReader cancels a context when read, to cancel an upload midway.
+type cancelReader struct {
+	cancel context.CancelFunc
+}
+
+func (r cancelReader) Read(p []byte) (int, error) {
+	r.cancel()
+	return 0, io.EOF
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"context"
+	"io"
+)
+
+// contextReader stops reading once ctx is done, so copies between readers and
+// writers that know nothing about contexts can still be cancelled.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+type contextReadCloser struct {
+	contextReader
+	io.Closer
+}
+
+func withContext(ctx context.Context, rc io.ReadCloser) io.ReadCloser {
+	return contextReadCloser{contextReader: contextReader{ctx: ctx, r: rc}, Closer: rc}
+}
+
+// DownloadToIn streams length bytes of the object starting at offset into w
+// and returns the number of bytes written. A negative length copies to the end
+// of the object.
+func DownloadToIn(ctx context.Context, store ObjectStore, objectName string, w io.Writer, offset int64, length int64) (int64, error) {
+	reader, err := store.NewRangeReader(ctx, objectName, offset, length)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+	return io.Copy(w, contextReader{ctx: ctx, r: reader})
+}
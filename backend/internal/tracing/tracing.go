@@ -0,0 +1,113 @@
+// Package tracing sets up OpenTelemetry tracing, so that a request can be
+// followed from the browser through the database and the bucket.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName names the tracer that spans of this module are started
+// with.
+const InstrumentationName = "github.com/Yongbeom-Kim/transfer/backend"
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+type Config struct {
+	// Exporter is where spans are sent: ExporterNone, ExporterStdout or
+	// ExporterOTLP. The OTLP endpoint is read by the exporter from the
+	// standard OTEL_EXPORTER_OTLP_* variables.
+	Exporter    string
+	ServiceName string
+	// SampleRatio is the share of traces started here that are recorded.
+	// Traces started by the caller follow the caller's sampling decision.
+	SampleRatio float64
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Exporter:    ExporterNone,
+		ServiceName: "transfer-backend",
+		SampleRatio: 1,
+	}
+}
+
+// ConfigFromEnv reads TRACING_EXPORTER, TRACING_SAMPLE_RATIO and
+// OTEL_SERVICE_NAME, falling back to DefaultConfig for unset variables.
+func ConfigFromEnv(getenv func(string) string) (Config, error) {
+	config := DefaultConfig()
+	if value := getenv("TRACING_EXPORTER"); value != "" {
+		if value != ExporterNone && value != ExporterStdout && value != ExporterOTLP {
+			return config, fmt.Errorf("invalid TRACING_EXPORTER: %q", value)
+		}
+		config.Exporter = value
+	}
+	if value := getenv("TRACING_SAMPLE_RATIO"); value != "" {
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return config, fmt.Errorf("invalid TRACING_SAMPLE_RATIO: %q", value)
+		}
+		config.SampleRatio = ratio
+	}
+	if value := getenv("OTEL_SERVICE_NAME"); value != "" {
+		config.ServiceName = value
+	}
+	return config, nil
+}
+
+// Init installs the global tracer provider and the W3C trace context
+// propagator. The returned function flushes and stops the exporter. With
+// ExporterNone, spans are not recorded, but trace context is still passed on.
+func Init(ctx context.Context, config Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch config.Exporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		err = fmt.Errorf("unknown exporter %q", config.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating %s exporter: %w", config.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(config.ServiceName)))
+	if errors.Is(err, resource.ErrSchemaURLConflict) {
+		res = resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(config.ServiceName))
+	} else if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer of this module from the global provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
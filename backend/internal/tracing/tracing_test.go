@@ -0,0 +1,47 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("TRACING_EXPORTER", "stdout")
+	t.Setenv("TRACING_SAMPLE_RATIO", "0.25")
+	t.Setenv("OTEL_SERVICE_NAME", "transfer-test")
+
+	config, err := ConfigFromEnv(os.Getenv)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := Config{Exporter: ExporterStdout, ServiceName: "transfer-test", SampleRatio: 0.25}
+	if config != expected {
+		t.Fatalf("Expected %+v, got %+v", expected, config)
+	}
+}
+
+func TestConfigFromEnv_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"TRACING_EXPORTER":     "jaeger",
+		"TRACING_SAMPLE_RATIO": "2",
+	}
+	for name, value := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			if _, err := ConfigFromEnv(os.Getenv); err == nil {
+				t.Fatalf("Expected error for %s=%q, got nil", name, value)
+			}
+		})
+	}
+}
+
+func TestInit_None(t *testing.T) {
+	shutdown, err := Init(context.Background(), DefaultConfig())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("Expected no error from shutdown, got %v", err)
+	}
+}
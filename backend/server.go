@@ -1,37 +1,61 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"net/http"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/Yongbeom-Kim/transfer/backend/internal/middleware"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/app"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/config"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/db"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/metrics"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/storage"
+	"github.com/Yongbeom-Kim/transfer/backend/internal/tracing"
 )
 
-func health(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("OK"))
-}
-
 func main() {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/health", health)
+	cfg, err := config.Load(os.Getenv)
+	if err != nil {
+		fmt.Printf("Error reading config:\n%s\n", err)
+		os.Exit(1)
+	}
+
+	pool, closePool, err := db.InitDBPool(cfg.Database)
+	if err != nil {
+		fmt.Printf("Error connecting to database: %s\n", err)
+		os.Exit(1)
+	}
+	defer closePool()
 
-	port := os.Getenv("BACKEND_PORT")
-	if port == "" {
-		port = "8080"
+	store, err := storage.New(context.Background(), cfg.Storage)
+	if err != nil {
+		fmt.Printf("Error setting up storage: %s\n", err)
+		os.Exit(1)
 	}
 
-	fmt.Printf("Starting server on port %s\n", port)
-	err := http.ListenAndServe(":"+port,
-		middleware.Compose(
-			middleware.CORSMiddleware,
-			middleware.Logger,
-		)(mux),
-	)
-	if err == http.ErrServerClosed {
-		fmt.Println("Server closed")
-	} else if err != nil {
-		fmt.Printf("Error starting server: %s\n", err)
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
+	if err != nil {
+		fmt.Printf("Error setting up tracing: %s\n", err)
+		os.Exit(1)
+	}
+	defer shutdownTracing(context.Background())
+
+	metrics.Registry.MustRegister(metrics.NewPoolCollector(pool), metrics.NewUploadsCollector(pool))
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		// A second signal kills the server without waiting for the drain
+		<-ctx.Done()
+		stop()
+	}()
+
+	if err := app.New(cfg, pool, store, slog.Default()).Run(ctx); err != nil {
+		fmt.Printf("Error running server: %s\n", err)
+	}
+	if err := store.Close(); err != nil {
+		fmt.Printf("Error closing storage: %s\n", err)
 	}
 }
@@ -0,0 +1,19 @@
+-- Deploy db:add_upload_owners to cockroach
+-- requires: create_auth_schema
+-- requires: create_upload_tables
+
+BEGIN;
+
+-- Users are created the first time a subject authenticates
+CREATE TABLE auth.users (
+    id UUID PRIMARY KEY,
+    subject TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Uploads created before owners were recorded have no owner, and are not
+-- visible to any user
+ALTER TABLE upload.uploads ADD COLUMN owner_id UUID REFERENCES auth.users(id) ON UPDATE CASCADE ON DELETE CASCADE;
+CREATE INDEX uploads_owner_id_idx ON upload.uploads (owner_id);
+
+COMMIT;
@@ -0,0 +1,13 @@
+-- Deploy db:add_upload_updated_at to cockroach
+-- requires: create_upload_tables
+
+BEGIN;
+
+-- When an upload last made progress. Uploads that existed before it was
+-- recorded start from the time of this change.
+ALTER TABLE upload.uploads ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+
+-- Find uploads that have gone without activity for too long
+CREATE INDEX uploads_updated_at_idx ON upload.uploads (updated_at);
+
+COMMIT;
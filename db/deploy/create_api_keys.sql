@@ -0,0 +1,49 @@
+-- Deploy db:create_api_keys to cockroach
+-- requires: create_auth_schema
+
+BEGIN;
+
+-- API keys for calling the backend. Only a hash of the key is stored.
+CREATE TABLE auth.api_keys (
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    key_hash BYTEA NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL DEFAULT ARRAY[]::TEXT[],
+    expires_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Procedure: Create an API key
+CREATE PROCEDURE auth.create_api_key(
+    p_id UUID,
+    p_name TEXT,
+    p_subject TEXT,
+    p_key_hash BYTEA,
+    p_scopes TEXT[],
+    p_expires_at TIMESTAMP WITH TIME ZONE
+) AS $$
+BEGIN
+    INSERT INTO auth.api_keys (id, name, subject, key_hash, scopes, expires_at)
+    VALUES (p_id, p_name, p_subject, p_key_hash, p_scopes, p_expires_at);
+END
+$$ LANGUAGE plpgsql;
+
+-- Procedure: Revoke an API key
+CREATE PROCEDURE auth.revoke_api_key(
+    p_id UUID
+) AS $$
+DECLARE
+    revoked_id UUID := NULL;
+BEGIN
+    UPDATE auth.api_keys SET revoked_at = now()
+    WHERE id = p_id AND revoked_at IS NULL
+    RETURNING id INTO revoked_id;
+    IF revoked_id IS NULL THEN
+        RAISE EXCEPTION 'API key not found: %', p_id;
+    END IF;
+END
+$$ LANGUAGE plpgsql;
+
+COMMIT;
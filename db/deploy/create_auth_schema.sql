@@ -0,0 +1,7 @@
+-- Deploy db:create_auth_schema to cockroach
+
+BEGIN;
+
+CREATE SCHEMA auth;
+
+COMMIT;
@@ -0,0 +1,13 @@
+-- Deploy db:create_leases to cockroach
+-- requires: create_upload_tables
+
+BEGIN;
+
+-- Leases let one replica at a time run a background job
+CREATE TABLE upload.leases (
+    name TEXT PRIMARY KEY,
+    holder TEXT NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+COMMIT;
@@ -0,0 +1,86 @@
+-- Deploy db:create_quotas to cockroach
+-- requires: update_procedures_upload_owner
+
+BEGIN;
+
+-- Limits on what a user may store. A NULL limit is unlimited. Users without a
+-- row of their own get the default quota.
+CREATE TABLE auth.quotas (
+    user_id UUID PRIMARY KEY REFERENCES auth.users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    max_total_bytes BIGINT,
+    max_file_size BIGINT,
+    max_concurrent_uploads INT,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE auth.default_quota (
+    id BOOL PRIMARY KEY DEFAULT true CHECK (id),
+    max_total_bytes BIGINT,
+    max_file_size BIGINT,
+    max_concurrent_uploads INT
+);
+
+INSERT INTO auth.default_quota (max_total_bytes, max_file_size, max_concurrent_uploads)
+VALUES (10737418240, 5368709120, 10);
+
+-- Procedure: Create new upload owned by a user, within the user's quota
+CREATE OR REPLACE PROCEDURE upload.create_new_upload(
+    p_id UUID,
+    p_owner_id UUID,
+    p_parts_count INT,
+    p_size INT,
+    p_mime_type TEXT
+) AS $$
+DECLARE
+    base_object_key TEXT;
+    locked_user UUID := NULL;
+    limit_total_bytes BIGINT;
+    limit_file_size BIGINT;
+    limit_concurrent_uploads INT;
+    used_bytes BIGINT;
+    active_uploads INT;
+BEGIN
+    IF p_owner_id IS NULL THEN
+        RAISE EXCEPTION 'Owner ID is required';
+    END IF;
+
+    -- Lock the owner so that concurrent creates are checked one at a time
+    SELECT id INTO locked_user FROM auth.users WHERE id = p_owner_id FOR UPDATE;
+    IF locked_user IS NULL THEN
+        RAISE EXCEPTION 'User not found: %', p_owner_id;
+    END IF;
+
+    SELECT q.max_total_bytes, q.max_file_size, q.max_concurrent_uploads
+        INTO limit_total_bytes, limit_file_size, limit_concurrent_uploads
+        FROM auth.quotas q WHERE q.user_id = p_owner_id;
+    IF NOT FOUND THEN
+        SELECT d.max_total_bytes, d.max_file_size, d.max_concurrent_uploads
+            INTO limit_total_bytes, limit_file_size, limit_concurrent_uploads
+            FROM auth.default_quota d;
+    END IF;
+
+    IF limit_file_size IS NOT NULL AND p_size > limit_file_size THEN
+        RAISE EXCEPTION 'Quota exceeded: max_file_size';
+    END IF;
+    SELECT COALESCE(SUM(size), 0), COUNT(*) FILTER (WHERE status IN ('pending', 'in_progress'))
+        INTO used_bytes, active_uploads
+        FROM upload.uploads WHERE owner_id = p_owner_id;
+    IF limit_total_bytes IS NOT NULL AND used_bytes + p_size > limit_total_bytes THEN
+        RAISE EXCEPTION 'Quota exceeded: max_total_bytes';
+    END IF;
+    IF limit_concurrent_uploads IS NOT NULL AND active_uploads >= limit_concurrent_uploads THEN
+        RAISE EXCEPTION 'Quota exceeded: max_concurrent_uploads';
+    END IF;
+
+    INSERT INTO upload.uploads (id, owner_id, parts_count, size, mime_type, status)
+    VALUES (p_id, p_owner_id, p_parts_count, p_size, p_mime_type, 'pending');
+
+    base_object_key := 'upload-' || p_id;
+    FOR part IN 0..(p_parts_count-1) LOOP
+        INSERT INTO upload.parts (upload_id, part_number, object_key, status)
+        VALUES (p_id, part, base_object_key || '-' || part, 'pending');
+    END LOOP;
+END
+$$ LANGUAGE plpgsql;
+
+COMMIT;
@@ -0,0 +1,17 @@
+-- Deploy db:create_rate_limits to cockroach
+-- requires: create_auth_schema
+
+BEGIN;
+
+-- Token buckets shared by all replicas. A bucket holds at most its burst, and
+-- may go negative when bytes are charged after they were transferred.
+CREATE TABLE auth.rate_limit_buckets (
+    key TEXT PRIMARY KEY,
+    tokens FLOAT8 NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Find buckets that have been idle long enough to be full again
+CREATE INDEX rate_limit_buckets_updated_at_idx ON auth.rate_limit_buckets (updated_at);
+
+COMMIT;
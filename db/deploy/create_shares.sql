@@ -0,0 +1,44 @@
+-- Deploy db:create_shares to cockroach
+-- requires: create_upload_objects
+
+BEGIN;
+
+-- Links for downloading a finalized upload. Only a hash of the token is
+-- stored, so the table cannot be used to download anything.
+CREATE TABLE upload.shares (
+    id UUID PRIMARY KEY,
+    upload_id UUID NOT NULL REFERENCES upload.uploads(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    token_hash BYTEA NOT NULL UNIQUE,
+    password_hash TEXT,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    max_downloads INT,
+    download_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Procedure: Create a share for a finalized upload
+CREATE PROCEDURE upload.create_share(
+    p_id UUID,
+    p_upload_id UUID,
+    p_token_hash BYTEA,
+    p_password_hash TEXT,
+    p_expires_at TIMESTAMP WITH TIME ZONE,
+    p_max_downloads INT
+) AS $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM upload.uploads WHERE id = p_upload_id) THEN
+        RAISE EXCEPTION 'Upload not found: %', p_upload_id;
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM upload.objects WHERE upload_id = p_upload_id) THEN
+        RAISE EXCEPTION 'Upload not finalized: %', p_upload_id;
+    END IF;
+    IF p_max_downloads IS NOT NULL AND p_max_downloads <= 0 THEN
+        RAISE EXCEPTION 'Max downloads must be positive';
+    END IF;
+
+    INSERT INTO upload.shares (id, upload_id, token_hash, password_hash, expires_at, max_downloads)
+    VALUES (p_id, p_upload_id, p_token_hash, p_password_hash, p_expires_at, p_max_downloads);
+END
+$$ LANGUAGE plpgsql;
+
+COMMIT;
@@ -0,0 +1,75 @@
+-- Deploy db:create_tus_uploads to cockroach
+-- requires: create_upload_objects
+
+BEGIN;
+
+-- tus uploads are single part uploads whose data arrives as a series of
+-- segments, each stored as its own object until the upload is complete.
+CREATE TABLE upload.tus_uploads (
+    upload_id UUID PRIMARY KEY REFERENCES upload.uploads(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    upload_offset BIGINT NOT NULL DEFAULT 0,
+    metadata TEXT NOT NULL DEFAULT '',
+    -- serialized SHA256 state of the bytes received so far
+    hash_state BYTEA,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE upload.tus_segments (
+    upload_id UUID NOT NULL REFERENCES upload.tus_uploads(upload_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    byte_offset BIGINT NOT NULL,
+    byte_size BIGINT NOT NULL,
+    object_key TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (upload_id, byte_offset)
+);
+
+-- Procedure: Create a single part upload driven by the tus protocol
+CREATE PROCEDURE upload.create_tus_upload(
+    p_id UUID,
+    p_size INT,
+    p_mime_type TEXT,
+    p_metadata TEXT,
+    p_expires_at TIMESTAMP WITH TIME ZONE
+) AS $$
+BEGIN
+    CALL upload.create_new_upload(p_id, 1, p_size, p_mime_type);
+    INSERT INTO upload.tus_uploads (upload_id, metadata, expires_at)
+    VALUES (p_id, p_metadata, p_expires_at);
+END
+$$ LANGUAGE plpgsql;
+
+-- Procedure: Record a segment, advancing the offset only if no other segment
+-- was recorded at the same offset first
+CREATE PROCEDURE upload.append_tus_segment(
+    p_upload_id UUID,
+    p_byte_offset BIGINT,
+    p_byte_size BIGINT,
+    p_object_key TEXT,
+    p_hash_state BYTEA
+) AS $$
+DECLARE
+    upload_updated UUID := NULL;
+BEGIN
+    UPDATE upload.tus_uploads
+        SET upload_offset = p_byte_offset + p_byte_size,
+            hash_state = p_hash_state
+        WHERE upload_id = p_upload_id AND upload_offset = p_byte_offset
+        RETURNING upload_id INTO upload_updated;
+    IF upload_updated IS NULL THEN
+        RAISE EXCEPTION 'Upload offset mismatch: %, %', p_upload_id, p_byte_offset;
+    END IF;
+    INSERT INTO upload.tus_segments (upload_id, byte_offset, byte_size, object_key)
+    VALUES (p_upload_id, p_byte_offset, p_byte_size, p_object_key);
+END
+$$ LANGUAGE plpgsql;
+
+-- Get keys for tus segments
+CREATE FUNCTION upload.get_tus_segment_object_keys(
+    p_upload_id UUID
+) RETURNS SETOF TEXT AS $$
+    SELECT object_key FROM upload.tus_segments WHERE upload_id = p_upload_id ORDER BY byte_offset;
+$$ LANGUAGE sql;
+
+COMMIT;
@@ -0,0 +1,48 @@
+-- Deploy db:create_upload_objects to cockroach
+-- requires: update_part_allow_zero_offset
+
+BEGIN;
+
+-- The object assembled from the parts of a completed upload
+CREATE TABLE upload.objects (
+    upload_id UUID PRIMARY KEY REFERENCES upload.uploads(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    object_key TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    sha256 BYTEA NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Procedure: Record the final object of a completed upload
+CREATE PROCEDURE upload.finalize_upload(
+    p_upload_id UUID,
+    p_object_key TEXT,
+    p_size BIGINT,
+    p_sha256 BYTEA
+) AS $$
+DECLARE
+    upload_status upload.upload_status := NULL;
+    upload_size INT := NULL;
+BEGIN
+    SELECT status, size INTO upload_status, upload_size FROM upload.uploads WHERE id = p_upload_id;
+    IF upload_status IS NULL THEN
+        RAISE EXCEPTION 'Upload not found: %', p_upload_id;
+    END IF;
+    IF upload_status != 'completed' THEN
+        RAISE EXCEPTION 'Upload not completed: %', p_upload_id;
+    END IF;
+    IF p_size != upload_size THEN
+        RAISE EXCEPTION 'Object size does not match upload size: %, %', p_size, upload_size;
+    END IF;
+    IF p_sha256 IS NULL OR p_sha256 = '' THEN
+        RAISE EXCEPTION 'SHA256 is required';
+    END IF;
+    IF EXISTS (SELECT 1 FROM upload.objects WHERE upload_id = p_upload_id) THEN
+        RAISE EXCEPTION 'Upload already finalized: %', p_upload_id;
+    END IF;
+
+    INSERT INTO upload.objects (upload_id, object_key, size, sha256)
+    VALUES (p_upload_id, p_object_key, p_size, p_sha256);
+END
+$$ LANGUAGE plpgsql;
+
+COMMIT;
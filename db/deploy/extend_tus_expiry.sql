@@ -0,0 +1,36 @@
+-- Deploy db:extend_tus_expiry to cockroach
+-- requires: create_tus_uploads
+
+BEGIN;
+
+DROP PROCEDURE upload.append_tus_segment;
+
+-- Procedure: Record a segment, advancing the offset only if no other segment
+-- was recorded at the same offset first. Every segment pushes the expiry of
+-- the upload back, so that uploads expire only once they stop making progress.
+CREATE PROCEDURE upload.append_tus_segment(
+    p_upload_id UUID,
+    p_byte_offset BIGINT,
+    p_byte_size BIGINT,
+    p_object_key TEXT,
+    p_hash_state BYTEA,
+    p_expires_at TIMESTAMP WITH TIME ZONE
+) AS $$
+DECLARE
+    upload_updated UUID := NULL;
+BEGIN
+    UPDATE upload.tus_uploads
+        SET upload_offset = p_byte_offset + p_byte_size,
+            hash_state = p_hash_state,
+            expires_at = greatest(expires_at, p_expires_at)
+        WHERE upload_id = p_upload_id AND upload_offset = p_byte_offset
+        RETURNING upload_id INTO upload_updated;
+    IF upload_updated IS NULL THEN
+        RAISE EXCEPTION 'Upload offset mismatch: %, %', p_upload_id, p_byte_offset;
+    END IF;
+    INSERT INTO upload.tus_segments (upload_id, byte_offset, byte_size, object_key)
+    VALUES (p_upload_id, p_byte_offset, p_byte_size, p_object_key);
+END
+$$ LANGUAGE plpgsql;
+
+COMMIT;
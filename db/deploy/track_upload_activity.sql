@@ -0,0 +1,120 @@
+-- Deploy db:track_upload_activity to cockroach
+-- requires: add_upload_updated_at
+-- requires: update_part_allow_zero_offset
+-- requires: extend_tus_expiry
+
+BEGIN;
+
+-- Updating a part or appending a tus segment records activity on the upload,
+-- and both are refused once the garbage collector has claimed the upload by
+-- marking it failed.
+CREATE OR REPLACE PROCEDURE upload.update_part(
+    p_upload_id UUID,
+    p_part_number INT,
+    p_status upload.part_status,
+    p_object_key TEXT,
+    p_byte_offset BIGINT,
+    p_byte_size BIGINT,
+    p_sha256 BYTEA
+) AS $$
+DECLARE
+    part_updated UUID := NULL;
+BEGIN
+    IF p_upload_id IS NULL THEN
+        RAISE EXCEPTION 'Upload ID is required';
+    END IF;
+    IF p_part_number IS NULL THEN
+        RAISE EXCEPTION 'Part number is required';
+    END IF;
+    IF p_status IS NULL THEN
+        RAISE EXCEPTION 'Part status is required';
+    END IF;
+    IF EXISTS (SELECT 1 FROM upload.uploads WHERE id = p_upload_id AND status = 'failed') THEN
+        RAISE EXCEPTION 'Upload failed: %', p_upload_id;
+    END IF;
+    IF p_status = 'uploaded' THEN
+        IF p_byte_offset IS NULL OR p_byte_offset < 0 THEN
+            RAISE EXCEPTION 'Byte offset is required if part status is uploaded';
+        END IF;
+        IF p_byte_size IS NULL OR p_byte_size = 0 THEN
+            RAISE EXCEPTION 'Byte size is required if part status is uploaded';
+        END IF;
+        IF p_sha256 IS NULL OR p_sha256 = '' THEN
+            RAISE EXCEPTION 'SHA256 is required if part status is uploaded';
+        END IF;
+    END IF;
+
+    UPDATE upload.parts
+        SET status = p_status,
+            object_key = p_object_key,
+            byte_offset = p_byte_offset,
+            byte_size = p_byte_size,
+            sha256 = p_sha256,
+            uploaded_at = CASE WHEN p_status = 'uploaded' THEN now() ELSE NULL END
+        WHERE upload_id = p_upload_id AND part_number = p_part_number
+        RETURNING upload_id INTO part_updated;
+    IF part_updated IS NULL THEN
+        RAISE EXCEPTION 'Part status not found: %, %', p_upload_id, p_part_number;
+    END IF;
+    IF (SELECT COUNT(*) FROM upload.parts WHERE upload_id = p_upload_id AND status != 'uploaded') = 0 THEN
+        UPDATE upload.uploads
+        SET status = 'completed', updated_at = now()
+        WHERE id = p_upload_id;
+    ELSE
+        UPDATE upload.uploads
+        SET status = 'in_progress', updated_at = now()
+        WHERE id = p_upload_id;
+    END IF;
+END
+$$ LANGUAGE plpgsql;
+
+DROP PROCEDURE upload.append_tus_segment;
+
+CREATE PROCEDURE upload.append_tus_segment(
+    p_upload_id UUID,
+    p_byte_offset BIGINT,
+    p_byte_size BIGINT,
+    p_object_key TEXT,
+    p_hash_state BYTEA,
+    p_expires_at TIMESTAMP WITH TIME ZONE
+) AS $$
+DECLARE
+    upload_updated UUID := NULL;
+BEGIN
+    IF EXISTS (SELECT 1 FROM upload.uploads WHERE id = p_upload_id AND status = 'failed') THEN
+        RAISE EXCEPTION 'Upload failed: %', p_upload_id;
+    END IF;
+    UPDATE upload.tus_uploads
+        SET upload_offset = p_byte_offset + p_byte_size,
+            hash_state = p_hash_state,
+            expires_at = greatest(expires_at, p_expires_at)
+        WHERE upload_id = p_upload_id AND upload_offset = p_byte_offset
+        RETURNING upload_id INTO upload_updated;
+    IF upload_updated IS NULL THEN
+        RAISE EXCEPTION 'Upload offset mismatch: %, %', p_upload_id, p_byte_offset;
+    END IF;
+    INSERT INTO upload.tus_segments (upload_id, byte_offset, byte_size, object_key)
+    VALUES (p_upload_id, p_byte_offset, p_byte_size, p_object_key);
+    UPDATE upload.uploads SET updated_at = now() WHERE id = p_upload_id;
+END
+$$ LANGUAGE plpgsql;
+
+-- Procedure: Record activity on an upload that is about to be finalized, so
+-- that the garbage collector leaves it alone meanwhile
+CREATE PROCEDURE upload.touch_upload(
+    p_upload_id UUID
+) AS $$
+DECLARE
+    upload_updated UUID := NULL;
+BEGIN
+    UPDATE upload.uploads
+        SET updated_at = now()
+        WHERE id = p_upload_id AND status != 'failed'
+        RETURNING id INTO upload_updated;
+    IF upload_updated IS NULL THEN
+        RAISE EXCEPTION 'Upload not found: %', p_upload_id;
+    END IF;
+END
+$$ LANGUAGE plpgsql;
+
+COMMIT;
@@ -0,0 +1,67 @@
+-- Deploy db:update_procedures_upload_owner to cockroach
+-- requires: add_upload_owners
+-- requires: create_tus_uploads
+
+BEGIN;
+
+DROP PROCEDURE upload.create_tus_upload;
+DROP PROCEDURE upload.create_new_upload;
+
+-- Procedure: Create new upload owned by a user
+CREATE PROCEDURE upload.create_new_upload(
+    p_id UUID,
+    p_owner_id UUID,
+    p_parts_count INT,
+    p_size INT,
+    p_mime_type TEXT
+) AS $$
+DECLARE
+    base_object_key TEXT;
+BEGIN
+    IF p_owner_id IS NULL THEN
+        RAISE EXCEPTION 'Owner ID is required';
+    END IF;
+    INSERT INTO upload.uploads (id, owner_id, parts_count, size, mime_type, status)
+    VALUES (p_id, p_owner_id, p_parts_count, p_size, p_mime_type, 'pending');
+
+    base_object_key := 'upload-' || p_id;
+    FOR part IN 0..(p_parts_count-1) LOOP
+        INSERT INTO upload.parts (upload_id, part_number, object_key, status)
+        VALUES (p_id, part, base_object_key || '-' || part, 'pending');
+    END LOOP;
+END
+$$ LANGUAGE plpgsql;
+
+-- Procedure: Create a single part upload driven by the tus protocol
+CREATE PROCEDURE upload.create_tus_upload(
+    p_id UUID,
+    p_owner_id UUID,
+    p_size INT,
+    p_mime_type TEXT,
+    p_metadata TEXT,
+    p_expires_at TIMESTAMP WITH TIME ZONE
+) AS $$
+BEGIN
+    CALL upload.create_new_upload(p_id, p_owner_id, 1, p_size, p_mime_type);
+    INSERT INTO upload.tus_uploads (upload_id, metadata, expires_at)
+    VALUES (p_id, p_metadata, p_expires_at);
+END
+$$ LANGUAGE plpgsql;
+
+-- Procedure: Delete an upload if it belongs to the owner
+CREATE PROCEDURE upload.delete_owned_upload(
+    p_upload_id UUID,
+    p_owner_id UUID
+) AS $$
+DECLARE
+    deleted_id UUID := NULL;
+BEGIN
+    DELETE FROM upload.uploads WHERE id = p_upload_id AND owner_id = p_owner_id RETURNING id INTO deleted_id;
+    IF deleted_id IS NULL THEN
+        RAISE EXCEPTION 'Upload not found: %', p_upload_id;
+    END IF;
+    -- Upload parts are deleted by ON DELETE CASCADE
+END
+$$ LANGUAGE plpgsql;
+
+COMMIT;
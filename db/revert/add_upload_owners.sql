@@ -0,0 +1,9 @@
+-- Revert db:add_upload_owners from cockroach
+
+BEGIN;
+
+DROP INDEX upload.uploads@uploads_owner_id_idx;
+ALTER TABLE upload.uploads DROP COLUMN owner_id;
+DROP TABLE auth.users;
+
+COMMIT;
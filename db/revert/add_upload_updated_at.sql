@@ -0,0 +1,8 @@
+-- Revert db:add_upload_updated_at from cockroach
+
+BEGIN;
+
+DROP INDEX upload.uploads@uploads_updated_at_idx;
+ALTER TABLE upload.uploads DROP COLUMN updated_at;
+
+COMMIT;
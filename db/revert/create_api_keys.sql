@@ -0,0 +1,9 @@
+-- Revert db:create_api_keys from cockroach
+
+BEGIN;
+
+DROP PROCEDURE auth.revoke_api_key;
+DROP PROCEDURE auth.create_api_key;
+DROP TABLE auth.api_keys;
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Revert db:create_auth_schema from cockroach
+
+BEGIN;
+
+DROP SCHEMA auth CASCADE;
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Revert db:create_leases from cockroach
+
+BEGIN;
+
+DROP TABLE upload.leases;
+
+COMMIT;
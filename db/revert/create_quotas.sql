@@ -0,0 +1,32 @@
+-- Revert db:create_quotas from cockroach
+
+BEGIN;
+
+CREATE OR REPLACE PROCEDURE upload.create_new_upload(
+    p_id UUID,
+    p_owner_id UUID,
+    p_parts_count INT,
+    p_size INT,
+    p_mime_type TEXT
+) AS $$
+DECLARE
+    base_object_key TEXT;
+BEGIN
+    IF p_owner_id IS NULL THEN
+        RAISE EXCEPTION 'Owner ID is required';
+    END IF;
+    INSERT INTO upload.uploads (id, owner_id, parts_count, size, mime_type, status)
+    VALUES (p_id, p_owner_id, p_parts_count, p_size, p_mime_type, 'pending');
+
+    base_object_key := 'upload-' || p_id;
+    FOR part IN 0..(p_parts_count-1) LOOP
+        INSERT INTO upload.parts (upload_id, part_number, object_key, status)
+        VALUES (p_id, part, base_object_key || '-' || part, 'pending');
+    END LOOP;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TABLE auth.default_quota;
+DROP TABLE auth.quotas;
+
+COMMIT;
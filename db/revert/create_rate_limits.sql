@@ -0,0 +1,7 @@
+-- Revert db:create_rate_limits from cockroach
+
+BEGIN;
+
+DROP TABLE auth.rate_limit_buckets;
+
+COMMIT;
@@ -0,0 +1,8 @@
+-- Revert db:create_shares from cockroach
+
+BEGIN;
+
+DROP PROCEDURE upload.create_share;
+DROP TABLE upload.shares;
+
+COMMIT;
@@ -0,0 +1,11 @@
+-- Revert db:create_tus_uploads from cockroach
+
+BEGIN;
+
+DROP FUNCTION upload.get_tus_segment_object_keys;
+DROP PROCEDURE upload.append_tus_segment;
+DROP PROCEDURE upload.create_tus_upload;
+DROP TABLE upload.tus_segments;
+DROP TABLE upload.tus_uploads;
+
+COMMIT;
@@ -0,0 +1,8 @@
+-- Revert db:create_upload_objects from cockroach
+
+BEGIN;
+
+DROP PROCEDURE upload.finalize_upload;
+DROP TABLE upload.objects;
+
+COMMIT;
@@ -0,0 +1,30 @@
+-- Revert db:extend_tus_expiry from cockroach
+
+BEGIN;
+
+DROP PROCEDURE upload.append_tus_segment;
+
+CREATE PROCEDURE upload.append_tus_segment(
+    p_upload_id UUID,
+    p_byte_offset BIGINT,
+    p_byte_size BIGINT,
+    p_object_key TEXT,
+    p_hash_state BYTEA
+) AS $$
+DECLARE
+    upload_updated UUID := NULL;
+BEGIN
+    UPDATE upload.tus_uploads
+        SET upload_offset = p_byte_offset + p_byte_size,
+            hash_state = p_hash_state
+        WHERE upload_id = p_upload_id AND upload_offset = p_byte_offset
+        RETURNING upload_id INTO upload_updated;
+    IF upload_updated IS NULL THEN
+        RAISE EXCEPTION 'Upload offset mismatch: %, %', p_upload_id, p_byte_offset;
+    END IF;
+    INSERT INTO upload.tus_segments (upload_id, byte_offset, byte_size, object_key)
+    VALUES (p_upload_id, p_byte_offset, p_byte_size, p_object_key);
+END
+$$ LANGUAGE plpgsql;
+
+COMMIT;
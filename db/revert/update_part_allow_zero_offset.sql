@@ -0,0 +1,61 @@
+-- Revert db:update_part_allow_zero_offset from cockroach
+
+BEGIN;
+
+CREATE OR REPLACE PROCEDURE upload.update_part(
+    p_upload_id UUID,
+    p_part_number INT,
+    p_status upload.part_status,
+    p_object_key TEXT,
+    p_byte_offset BIGINT,
+    p_byte_size BIGINT,
+    p_sha256 BYTEA
+) AS $$
+DECLARE
+    part_updated UUID := NULL;
+BEGIN
+    IF p_upload_id IS NULL THEN
+        RAISE EXCEPTION 'Upload ID is required';
+    END IF;
+    IF p_part_number IS NULL THEN
+        RAISE EXCEPTION 'Part number is required';
+    END IF;
+    IF p_status IS NULL THEN
+        RAISE EXCEPTION 'Part status is required';
+    END IF;
+    IF p_status = 'uploaded' THEN
+        IF p_byte_offset IS NULL OR p_byte_offset = 0 THEN
+            RAISE EXCEPTION 'Byte offset is required if part status is uploaded';
+        END IF;
+        IF p_byte_size IS NULL OR p_byte_size = 0 THEN
+            RAISE EXCEPTION 'Byte size is required if part status is uploaded';
+        END IF;
+        IF p_sha256 IS NULL OR p_sha256 = '' THEN
+            RAISE EXCEPTION 'SHA256 is required if part status is uploaded';
+        END IF;
+    END IF;
+
+    UPDATE upload.parts
+        SET status = p_status,
+            object_key = p_object_key,
+            byte_offset = p_byte_offset,
+            byte_size = p_byte_size,
+            sha256 = p_sha256
+        WHERE upload_id = p_upload_id AND part_number = p_part_number
+        RETURNING upload_id INTO part_updated;
+    IF part_updated IS NULL THEN
+        RAISE EXCEPTION 'Part status not found: %, %', p_upload_id, p_part_number;
+    END IF;
+    IF (SELECT COUNT(*) FROM upload.parts WHERE upload_id = p_upload_id AND status != 'uploaded') = 0 THEN
+        UPDATE upload.uploads
+        SET status = 'completed'
+        WHERE id = p_upload_id;
+    ELSE
+        UPDATE upload.uploads
+        SET status = 'in_progress'
+        WHERE id = p_upload_id;
+    END IF;
+END
+$$ LANGUAGE plpgsql;
+
+COMMIT;
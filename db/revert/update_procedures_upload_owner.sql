@@ -0,0 +1,43 @@
+-- Revert db:update_procedures_upload_owner from cockroach
+
+BEGIN;
+
+DROP PROCEDURE upload.delete_owned_upload;
+DROP PROCEDURE upload.create_tus_upload;
+DROP PROCEDURE upload.create_new_upload;
+
+CREATE PROCEDURE upload.create_new_upload(
+    p_id UUID,
+    p_parts_count INT,
+    p_size INT,
+    p_mime_type TEXT
+) AS $$
+DECLARE
+    base_object_key TEXT;
+BEGIN
+    INSERT INTO upload.uploads (id, parts_count, size, mime_type, status)
+    VALUES (p_id, p_parts_count, p_size, p_mime_type, 'pending');
+
+    base_object_key := 'upload-' || p_id;
+    FOR part IN 0..(p_parts_count-1) LOOP
+        INSERT INTO upload.parts (upload_id, part_number, object_key, status)
+        VALUES (p_id, part, base_object_key || '-' || part, 'pending');
+    END LOOP;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE PROCEDURE upload.create_tus_upload(
+    p_id UUID,
+    p_size INT,
+    p_mime_type TEXT,
+    p_metadata TEXT,
+    p_expires_at TIMESTAMP WITH TIME ZONE
+) AS $$
+BEGIN
+    CALL upload.create_new_upload(p_id, 1, p_size, p_mime_type);
+    INSERT INTO upload.tus_uploads (upload_id, metadata, expires_at)
+    VALUES (p_id, p_metadata, p_expires_at);
+END
+$$ LANGUAGE plpgsql;
+
+COMMIT;
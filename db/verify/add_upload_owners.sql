@@ -0,0 +1,13 @@
+-- Verify db:add_upload_owners on cockroach
+
+BEGIN;
+
+SELECT id, subject, created_at
+FROM auth.users
+WHERE 1=0;
+
+SELECT owner_id
+FROM upload.uploads
+WHERE 1=0;
+
+ROLLBACK;
@@ -0,0 +1,13 @@
+-- Verify db:add_upload_updated_at on cockroach
+
+BEGIN;
+
+SELECT updated_at
+FROM upload.uploads
+WHERE 1=0;
+
+SELECT updated_at
+FROM upload.uploads@uploads_updated_at_idx
+WHERE 1=0;
+
+ROLLBACK;
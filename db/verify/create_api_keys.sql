@@ -0,0 +1,22 @@
+-- Verify db:create_api_keys on cockroach
+
+BEGIN;
+
+SELECT id, name, subject, key_hash, scopes, expires_at, revoked_at, created_at
+FROM auth.api_keys
+WHERE 1=0;
+
+CREATE PROCEDURE auth.sqitch_verify_create_api_keys() language plpgsql as $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.routines WHERE routine_schema = 'auth' AND routine_name = 'create_api_key') THEN
+        RAISE EXCEPTION 'CREATE_API_KEY PROCEDURE DOES NOT EXIST';
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM information_schema.routines WHERE routine_schema = 'auth' AND routine_name = 'revoke_api_key') THEN
+        RAISE EXCEPTION 'REVOKE_API_KEY PROCEDURE DOES NOT EXIST';
+    END IF;
+END;
+$$;
+
+CALL auth.sqitch_verify_create_api_keys();
+
+ROLLBACK;
@@ -0,0 +1,15 @@
+-- Verify db:create_auth_schema on cockroach
+
+BEGIN;
+
+CREATE PROCEDURE auth.sqitch_verify_create_auth_schema() language plpgsql as $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.schemata WHERE schema_name = 'auth') THEN
+        RAISE EXCEPTION 'auth schema does not exist';
+    END IF;
+END;
+$$;
+
+CALL auth.sqitch_verify_create_auth_schema();
+
+ROLLBACK;
@@ -0,0 +1,9 @@
+-- Verify db:create_leases on cockroach
+
+BEGIN;
+
+SELECT name, holder, expires_at
+FROM upload.leases
+WHERE 1=0;
+
+ROLLBACK;
@@ -0,0 +1,13 @@
+-- Verify db:create_quotas on cockroach
+
+BEGIN;
+
+SELECT user_id, max_total_bytes, max_file_size, max_concurrent_uploads, updated_at
+FROM auth.quotas
+WHERE 1=0;
+
+SELECT max_total_bytes, max_file_size, max_concurrent_uploads
+FROM auth.default_quota
+WHERE 1=0;
+
+ROLLBACK;
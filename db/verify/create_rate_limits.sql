@@ -0,0 +1,9 @@
+-- Verify db:create_rate_limits on cockroach
+
+BEGIN;
+
+SELECT key, tokens, updated_at
+FROM auth.rate_limit_buckets
+WHERE 1=0;
+
+ROLLBACK;
@@ -0,0 +1,19 @@
+-- Verify db:create_shares on cockroach
+
+BEGIN;
+
+SELECT id, upload_id, token_hash, password_hash, expires_at, max_downloads, download_count, created_at
+FROM upload.shares
+WHERE 1=0;
+
+CREATE PROCEDURE upload.sqitch_verify_create_shares() language plpgsql as $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.routines WHERE routine_schema = 'upload' AND routine_name = 'create_share') THEN
+        RAISE EXCEPTION 'CREATE_SHARE PROCEDURE DOES NOT EXIST';
+    END IF;
+END;
+$$;
+
+CALL upload.sqitch_verify_create_shares();
+
+ROLLBACK;
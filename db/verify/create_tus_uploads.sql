@@ -0,0 +1,29 @@
+-- Verify db:create_tus_uploads on cockroach
+
+BEGIN;
+
+SELECT upload_id, upload_offset, metadata, hash_state, expires_at, created_at
+FROM upload.tus_uploads
+WHERE 1=0;
+
+SELECT upload_id, byte_offset, byte_size, object_key, created_at
+FROM upload.tus_segments
+WHERE 1=0;
+
+CREATE PROCEDURE upload.sqitch_verify_create_tus_uploads() language plpgsql as $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.routines WHERE routine_schema = 'upload' AND routine_name = 'create_tus_upload') THEN
+        RAISE EXCEPTION 'CREATE_TUS_UPLOAD PROCEDURE DOES NOT EXIST';
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM information_schema.routines WHERE routine_schema = 'upload' AND routine_name = 'append_tus_segment') THEN
+        RAISE EXCEPTION 'APPEND_TUS_SEGMENT PROCEDURE DOES NOT EXIST';
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM information_schema.routines WHERE routine_schema = 'upload' AND routine_name = 'get_tus_segment_object_keys') THEN
+        RAISE EXCEPTION 'GET_TUS_SEGMENT_OBJECT_KEYS FUNCTION DOES NOT EXIST';
+    END IF;
+END;
+$$;
+
+CALL upload.sqitch_verify_create_tus_uploads();
+
+ROLLBACK;
@@ -0,0 +1,19 @@
+-- Verify db:create_upload_objects on cockroach
+
+BEGIN;
+
+SELECT upload_id, object_key, size, sha256, created_at
+FROM upload.objects
+WHERE 1=0;
+
+CREATE PROCEDURE upload.sqitch_verify_create_upload_objects() language plpgsql as $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.routines WHERE routine_schema = 'upload' AND routine_name = 'finalize_upload') THEN
+        RAISE EXCEPTION 'FINALIZE_UPLOAD PROCEDURE DOES NOT EXIST';
+    END IF;
+END;
+$$;
+
+CALL upload.sqitch_verify_create_upload_objects();
+
+ROLLBACK;
@@ -0,0 +1,15 @@
+-- Verify db:extend_tus_expiry on cockroach
+
+BEGIN;
+
+CREATE PROCEDURE upload.sqitch_verify_extend_tus_expiry() language plpgsql as $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.routines WHERE routine_schema = 'upload' AND routine_name = 'append_tus_segment') THEN
+        RAISE EXCEPTION 'APPEND_TUS_SEGMENT PROCEDURE DOES NOT EXIST';
+    END IF;
+END;
+$$;
+
+CALL upload.sqitch_verify_extend_tus_expiry();
+
+ROLLBACK;
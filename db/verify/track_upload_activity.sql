@@ -0,0 +1,15 @@
+-- Verify db:track_upload_activity on cockroach
+
+BEGIN;
+
+CREATE PROCEDURE upload.sqitch_verify_track_upload_activity() language plpgsql as $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.routines WHERE routine_schema = 'upload' AND routine_name = 'touch_upload') THEN
+        RAISE EXCEPTION 'TOUCH_UPLOAD PROCEDURE DOES NOT EXIST';
+    END IF;
+END;
+$$;
+
+CALL upload.sqitch_verify_track_upload_activity();
+
+ROLLBACK;
@@ -0,0 +1,15 @@
+-- Verify db:update_part_allow_zero_offset on cockroach
+
+BEGIN;
+
+CREATE PROCEDURE upload.sqitch_verify_update_part_allow_zero_offset() language plpgsql as $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.routines WHERE routine_schema = 'upload' AND routine_name = 'update_part') THEN
+        RAISE EXCEPTION 'UPDATE_PART PROCEDURE DOES NOT EXIST';
+    END IF;
+END;
+$$;
+
+CALL upload.sqitch_verify_update_part_allow_zero_offset();
+
+ROLLBACK;
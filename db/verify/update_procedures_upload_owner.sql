@@ -0,0 +1,18 @@
+-- Verify db:update_procedures_upload_owner on cockroach
+
+BEGIN;
+
+CREATE PROCEDURE upload.sqitch_verify_update_procedures_upload_owner() language plpgsql as $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.routines WHERE routine_schema = 'upload' AND routine_name = 'create_new_upload') THEN
+        RAISE EXCEPTION 'CREATE_NEW_UPLOAD PROCEDURE DOES NOT EXIST';
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM information_schema.routines WHERE routine_schema = 'upload' AND routine_name = 'delete_owned_upload') THEN
+        RAISE EXCEPTION 'DELETE_OWNED_UPLOAD PROCEDURE DOES NOT EXIST';
+    END IF;
+END;
+$$;
+
+CALL upload.sqitch_verify_update_procedures_upload_owner();
+
+ROLLBACK;